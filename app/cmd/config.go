@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration with secrets redacted",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, setting := range cfg.Settings() {
+			value := setting.String()
+			if setting.Secret && value != "" {
+				value = "<redacted>"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%-34s %-28s %q\n", setting.Key, setting.Env, value)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/fajaaro/dbo/app"
+	"github.com/fajaaro/dbo/app/migrations"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, roll back and inspect schema migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending migration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s).", applied)
+		return migrations.SeedRoles(migrator.DB)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Roll back the last N migrations (default 1)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) == 1 {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down takes a positive number of migrations, got %q", args[0])
+			}
+		}
+
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		rolledBack, err := migrator.Down(n)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migration(s).", rolledBack)
+		return nil
+	},
+}
+
+var migrateRedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Roll back the last migration and apply it again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		err = migrator.Redo()
+		if err != nil {
+			return err
+		}
+		log.Println("Redid the last migration.")
+		// Redoing a migration that creates the roles table empties it.
+		return migrations.SeedRoles(migrator.DB)
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and when they were applied",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%04d  %-30s  %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Add an empty up/down migration pair to migrations.dir",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := migrations.Create(cfg.Migrations.Dir, args[0])
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			log.Println("Created " + path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateRedoCmd, migrateStatusCmd, migrateCreateCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// newMigrator connects to the database and loads the embedded migrations.
+func newMigrator() (*migrations.Migrator, error) {
+	db, err := app.Connect(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.NewMigrator(db)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/fajaaro/dbo/app"
+	"github.com/fajaaro/dbo/app/config"
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/denylist"
+	"github.com/fajaaro/dbo/app/keys"
+	"github.com/fajaaro/dbo/app/logging"
+	"github.com/fajaaro/dbo/app/mailer"
+	"github.com/fajaaro/dbo/app/password"
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// cfg is the configuration loaded before any command runs.
+var cfg *config.Config
+
+var rootCmd = &cobra.Command{
+	Use:          "dbo",
+	Short:        "Customer and order API",
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Variables already set in the environment win over the .env file,
+		// which is optional.
+		err := godotenv.Load()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		file, _ := cmd.Flags().GetString("config")
+		if file == "" {
+			file = os.Getenv("DBO_CONFIG")
+		}
+		cfg, err = config.Load(file, cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		logging.Setup(cfg.Log)
+		controllers.Config = cfg
+		return nil
+	},
+}
+
+// Execute runs the command named on the command line.
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "", "YAML or TOML config file (DBO_CONFIG)")
+	config.RegisterFlags(rootCmd.PersistentFlags())
+}
+
+// setup connects to the database and wires the token denylist, mailer,
+// signing keys and breached password list into the controllers.
+func setup() (*gorm.DB, error) {
+	db, err := app.Connect(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenDenylist, err := denylist.New(cfg.JWT.DenylistDriver, db)
+	if err != nil {
+		return nil, err
+	}
+	controllers.TokenDenylist = tokenDenylist
+
+	mail, err := mailer.New(cfg.Mail, db)
+	if err != nil {
+		return nil, err
+	}
+	controllers.Mailer = mail
+
+	if cfg.JWT.KeysDir != "" {
+		signingKeys, err := keys.LoadDir(cfg.JWT.KeysDir, cfg.JWT.SigningKID)
+		if err != nil {
+			return nil, err
+		}
+		controllers.SigningKeys = signingKeys
+	} else {
+		controllers.SigningKeys = keys.NewHMACManager([]byte(cfg.JWT.SecretKey))
+	}
+
+	if cfg.Password.BreachedList != "" {
+		breachedPasswords, err := password.Load(cfg.Password.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+		controllers.BreachedPasswords = breachedPasswords
+	}
+
+	return db, nil
+}
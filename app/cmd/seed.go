@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// fixtures is the format of the seed file: customers, each with its orders.
+type fixtures struct {
+	Customers []struct {
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		PhoneNumber string `json:"phone_number"`
+		Gender      string `json:"gender"`
+		Orders      []struct {
+			ProductName   string  `json:"product_name"`
+			Quantity      int     `json:"quantity"`
+			TotalPrice    float64 `json:"total_price"`
+			PaymentStatus string  `json:"payment_status"`
+		} `json:"orders"`
+	} `json:"customers"`
+}
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Load demo customers and orders from a fixtures file",
+	Long: "Load demo customers and orders from a fixtures file into a tenant. Customers whose\n" +
+		"email already exists in the tenant are skipped, so seeding twice is harmless.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		tenantID, _ := cmd.Flags().GetUint("tenant")
+
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		data := fixtures{}
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		db, err := setup()
+		if err != nil {
+			return err
+		}
+
+		customers, orders := 0, 0
+		err = db.Transaction(func(tx *gorm.DB) error {
+			tenant := models.Tenant{}
+			if tenantID != 0 {
+				err := tx.First(&tenant, tenantID).Error
+				if err != nil {
+					return fmt.Errorf("tenant %d: %w", tenantID, err)
+				}
+			} else {
+				err := tx.Where(models.Tenant{Name: "Demo"}).FirstOrCreate(&tenant).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, fixture := range data.Customers {
+				var count int64
+				err := tx.Model(&models.Customer{}).
+					Where("tenant_id = ? AND email = ?", tenant.ID, fixture.Email).
+					Count(&count).Error
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+
+				customer := models.Customer{
+					TenantID:    tenant.ID,
+					Name:        fixture.Name,
+					Email:       fixture.Email,
+					PhoneNumber: fixture.PhoneNumber,
+					Gender:      strings.ToLower(fixture.Gender),
+				}
+				if err := tx.Create(&customer).Error; err != nil {
+					return err
+				}
+				customers++
+
+				for _, orderFixture := range fixture.Orders {
+					order := models.Order{
+						TenantID:      tenant.ID,
+						CustomerID:    customer.ID,
+						ProductName:   orderFixture.ProductName,
+						Quantity:      orderFixture.Quantity,
+						TotalPrice:    orderFixture.TotalPrice,
+						PaymentStatus: strings.ToLower(orderFixture.PaymentStatus),
+					}
+					if order.PaymentStatus == "paid" {
+						paidAt := time.Now()
+						order.PaidAt = &paidAt
+					}
+					if err := tx.Create(&order).Error; err != nil {
+						return err
+					}
+					orders++
+				}
+			}
+			tenantID = tenant.ID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Seeded %d customer(s) and %d order(s) into tenant %d.\n", customers, orders, tenantID)
+		return nil
+	},
+}
+
+func init() {
+	seedCmd.Flags().String("file", "fixtures/demo.json", "fixtures file to load")
+	seedCmd.Flags().Uint("tenant", 0, "ID of the tenant to seed (default a tenant named Demo)")
+	rootCmd.AddCommand(seedCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/fajaaro/dbo/app/metrics"
+	"github.com/fajaaro/dbo/app/migrations"
+	"github.com/fajaaro/dbo/app/routers"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the HTTP API",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if addr, _ := cmd.Flags().GetString("addr"); addr != "" {
+			cfg.HTTP.Address = addr
+		}
+
+		db, err := setup()
+		if err != nil {
+			return err
+		}
+
+		if cfg.Migrations.OnStart {
+			err = migrations.Migrate(db)
+			if err != nil {
+				return err
+			}
+			slog.Info("migrations applied")
+		}
+
+		if cfg.Metrics.Enabled {
+			err = metrics.InstrumentDB(db, cfg.DB.Name, cfg.Metrics.Business)
+			if err != nil {
+				return err
+			}
+		}
+
+		r := routers.SetupRouter(db)
+		server := &http.Server{
+			Addr:              cfg.HTTP.Address,
+			Handler:           r,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+		}
+		errs := make(chan error, 2)
+		if cfg.Metrics.Enabled && cfg.Metrics.Address != "" {
+			go func() {
+				errs <- serveMetrics(cfg.Metrics.Address, cfg.Metrics.Path)
+			}()
+		}
+		go func() {
+			slog.Info("listening", slog.String("address", server.Addr))
+			errs <- server.ListenAndServe()
+		}()
+		return <-errs
+	},
+}
+
+// serveMetrics serves only the metrics at path on addr, an admin port kept
+// apart from the API.
+func serveMetrics(addr string, path string) error {
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+	}
+	slog.Info("serving metrics", slog.String("address", addr), slog.String("path", path))
+	return server.ListenAndServe()
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "", "shorthand for --http-address")
+	rootCmd.AddCommand(serveCmd)
+}
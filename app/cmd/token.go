@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Mint tokens for scripts",
+}
+
+var tokenIssueCmd = &cobra.Command{
+	Use:   "issue <email>",
+	Short: "Print an access token for a user",
+	Long: "Print an access token for a user. It is not tied to a login session, so it can only be\n" +
+		"revoked by waiting for it to expire or by the user's logout-all.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		if ttl <= 0 {
+			return fmt.Errorf("--ttl must be positive, got %s", ttl)
+		}
+
+		db, err := setup()
+		if err != nil {
+			return err
+		}
+		token, err := controllers.IssueAccessToken(db, args[0], ttl)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), token)
+		return nil
+	},
+}
+
+func init() {
+	tokenIssueCmd.Flags().Duration("ttl", time.Hour, "how long the token is valid")
+	tokenCmd.AddCommand(tokenIssueCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fajaaro/dbo/app"
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/spf13/cobra"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage user accounts",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <email>",
+	Short: "Add a user",
+	Long: "Add a user. Without --tenant the user starts a new tenant. The password is read from\n" +
+		"--password or the DBO_USER_PASSWORD environment variable so it stays out of shell history.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		password, _ := flags.GetString("password")
+		if password == "" {
+			password = os.Getenv("DBO_USER_PASSWORD")
+		}
+		if password == "" {
+			return errors.New("a password is required (--password or DBO_USER_PASSWORD)")
+		}
+		name, _ := flags.GetString("name")
+		tenantID, _ := flags.GetUint("tenant")
+		organization, _ := flags.GetString("organization")
+		admin, _ := flags.GetBool("admin")
+
+		db, err := setup()
+		if err != nil {
+			return err
+		}
+		user, err := controllers.CreateUser(db, controllers.NewUser{
+			Email:        args[0],
+			Password:     password,
+			Name:         name,
+			TenantID:     tenantID,
+			Organization: organization,
+			Admin:        admin,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Created user %d (%s) in tenant %d.\n", user.ID, user.Email, user.TenantID)
+		return nil
+	},
+}
+
+var bootstrapAdminCmd = &cobra.Command{
+	Use:   "bootstrap-admin <email>",
+	Short: "Grant the admin role to an existing user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := app.Connect(cfg.DB)
+		if err != nil {
+			return err
+		}
+		err = controllers.BootstrapAdmin(db, args[0])
+		if err != nil {
+			return err
+		}
+		log.Println("Granted admin role to " + args[0] + ".")
+		return nil
+	},
+}
+
+func init() {
+	flags := userCreateCmd.Flags()
+	flags.String("password", "", "password for the new user")
+	flags.String("name", "", "display name")
+	flags.Uint("tenant", 0, "ID of an existing tenant to add the user to")
+	flags.String("organization", "", "name of the new tenant when --tenant is not set (default the email)")
+	flags.Bool("admin", false, "grant the admin role instead of DEFAULT_ROLE")
+	userCmd.AddCommand(userCreateCmd)
+	rootCmd.AddCommand(userCmd, bootstrapAdminCmd)
+}
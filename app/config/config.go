@@ -0,0 +1,191 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+)
+
+// Config is every setting the app reads. Each field names its key in a config
+// file (the yaml tags, used for TOML too), its environment variable and its
+// command line flag, which is the file key with dashes, e.g. --db-host.
+type Config struct {
+	HTTP       HTTPConfig       `yaml:"http"`
+	DB         DBConfig         `yaml:"db"`
+	JWT        JWTConfig        `yaml:"jwt"`
+	Log        LogConfig        `yaml:"log"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Mail       MailConfig       `yaml:"mail"`
+	Login      LoginConfig      `yaml:"login"`
+	Password   PasswordConfig   `yaml:"password"`
+	Migrations MigrationsConfig `yaml:"migrations"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+}
+
+type HTTPConfig struct {
+	Address           string        `yaml:"address" env:"HTTP_ADDRESS" usage:"address to listen on"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" usage:"maximum time to read a request, 0 for none"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" usage:"maximum time to read request headers"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" usage:"maximum time to write a response, 0 for none"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" usage:"how long idle keep-alive connections stay open"`
+	TrustedProxies    string        `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" usage:"comma-separated IPs or CIDRs of proxies whose X-Forwarded-For is believed; empty trusts none"`
+}
+
+// TrustedProxyList splits TrustedProxies into its IPs and CIDRs.
+func (c HTTPConfig) TrustedProxyList() []string {
+	proxies := []string{}
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+type DBConfig struct {
+	Driver   string `yaml:"driver" env:"DB_DRIVER" usage:"postgres, mysql or sqlite"`
+	Host     string `yaml:"host" env:"DB_HOST" usage:"database host"`
+	Port     int    `yaml:"port" env:"DB_PORT" usage:"database port, 3306 for MySQL"`
+	Username string `yaml:"username" env:"DB_USERNAME" usage:"database user"`
+	Password string `yaml:"password" env:"DB_PASSWORD" secret:"true" usage:"database password"`
+	Name     string `yaml:"name" env:"DB_NAME" usage:"database name, or the database file for sqlite"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" usage:"Postgres only: disable, allow, prefer, require, verify-ca or verify-full"`
+	TimeZone string `yaml:"timezone" env:"DB_TIMEZONE" usage:"session time zone, e.g. UTC or Asia/Jakarta"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"maximum open connections, 0 for no limit"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" usage:"maximum idle connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"close connections older than this, 0 for never"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" usage:"close connections idle for longer than this, 0 for never"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" usage:"how long to keep retrying the first connection at startup"`
+}
+
+type JWTConfig struct {
+	SecretKey      string        `yaml:"secret_key" env:"SECRET_KEY" secret:"true" usage:"HS256 signing secret, used when keys_dir is empty"`
+	KeysDir        string        `yaml:"keys_dir" env:"JWT_KEYS_DIR" usage:"directory of PEM signing keys"`
+	SigningKID     string        `yaml:"signing_kid" env:"JWT_SIGNING_KID" usage:"key ID in keys_dir that signs new tokens"`
+	Issuer         string        `yaml:"issuer" env:"JWT_ISSUER" usage:"iss claim of issued tokens"`
+	Audience       string        `yaml:"audience" env:"JWT_AUDIENCE" usage:"aud claim of issued tokens"`
+	AccessTTL      time.Duration `yaml:"access_ttl" env:"JWT_ACCESS_TTL" usage:"lifetime of access tokens from a login"`
+	RefreshTTL     time.Duration `yaml:"refresh_ttl" env:"JWT_REFRESH_TTL" usage:"lifetime of refresh tokens"`
+	DenylistDriver string        `yaml:"denylist_driver" env:"TOKEN_DENYLIST_DRIVER" usage:"memory or database (postgres is an alias)"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" usage:"debug, info, warn or error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" usage:"text or json"`
+}
+
+type AuthConfig struct {
+	AppURL                   string `yaml:"app_url" env:"APP_URL" usage:"base URL used in emailed links"`
+	DefaultRole              string `yaml:"default_role" env:"DEFAULT_ROLE" usage:"role of users added without one"`
+	RequireEmailVerification bool   `yaml:"require_email_verification" env:"REQUIRE_EMAIL_VERIFICATION" usage:"refuse logins from unverified accounts"`
+}
+
+type MailConfig struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER" usage:"file, db or smtp"`
+	From         string `yaml:"from" env:"MAIL_FROM" usage:"sender address"`
+	OutboxDir    string `yaml:"outbox_dir" env:"MAIL_OUTBOX_DIR" usage:"where the file driver writes mail"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST" usage:"SMTP server host"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" usage:"SMTP server port"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME" usage:"SMTP user"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true" usage:"SMTP password"`
+}
+
+type LoginConfig struct {
+	MaxFailures     int           `yaml:"max_failures" env:"LOGIN_MAX_FAILURES" usage:"failures before an account is locked"`
+	IPMaxFailures   int           `yaml:"ip_max_failures" env:"LOGIN_IP_MAX_FAILURES" usage:"failures before a client IP is locked"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION" usage:"how long a lockout lasts"`
+	DelayBase       time.Duration `yaml:"delay_base" env:"LOGIN_DELAY_BASE" usage:"delay after the first failure, doubled per failure"`
+}
+
+type PasswordConfig struct {
+	MinLength     int    `yaml:"min_length" env:"PASSWORD_MIN_LENGTH" usage:"minimum password length"`
+	MaxLength     int    `yaml:"max_length" env:"PASSWORD_MAX_LENGTH" usage:"maximum password length, at most 72"`
+	RequireUpper  bool   `yaml:"require_upper" env:"PASSWORD_REQUIRE_UPPER" usage:"require an upper case letter"`
+	RequireLower  bool   `yaml:"require_lower" env:"PASSWORD_REQUIRE_LOWER" usage:"require a lower case letter"`
+	RequireDigit  bool   `yaml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT" usage:"require a digit"`
+	RequireSymbol bool   `yaml:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL" usage:"require a symbol"`
+	CheckBreached bool   `yaml:"check_breached" env:"PASSWORD_CHECK_BREACHED" usage:"reject common and breached passwords"`
+	BreachedList  string `yaml:"breached_list" env:"PASSWORD_BREACHED_LIST" usage:"file or directory of breached password hashes"`
+}
+
+type MigrationsConfig struct {
+	OnStart bool   `yaml:"on_start" env:"MIGRATE_ON_START" usage:"apply pending migrations when serve starts"`
+	Dir     string `yaml:"dir" env:"MIGRATIONS_DIR" usage:"where migrate create writes new files"`
+}
+
+type MetricsConfig struct {
+	Enabled  bool   `yaml:"enabled" env:"METRICS_ENABLED" usage:"serve Prometheus metrics"`
+	Address  string `yaml:"address" env:"METRICS_ADDRESS" usage:"separate address to serve metrics on, e.g. :9090; empty serves them with the API"`
+	Path     string `yaml:"path" env:"METRICS_PATH" usage:"path of the metrics endpoint"`
+	Business bool   `yaml:"business" env:"METRICS_BUSINESS" usage:"also export customer and order counts, queried on every scrape"`
+}
+
+// Default returns the settings used for anything not configured.
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Address:           ":8080",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		},
+		DB: DBConfig{
+			Driver:   "postgres",
+			Host:     "127.0.0.1",
+			Port:     5432,
+			Username: "postgres",
+			Name:     "dbo",
+			SSLMode:  "disable",
+			TimeZone: "UTC",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  30 * time.Second,
+		},
+		JWT: JWTConfig{
+			Issuer:         "dbo",
+			Audience:       "dbo-api",
+			AccessTTL:      15 * time.Minute,
+			RefreshTTL:     30 * 24 * time.Hour,
+			DenylistDriver: "memory",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Auth: AuthConfig{
+			AppURL:      "http://localhost:8080",
+			DefaultRole: models.RoleViewer,
+		},
+		Mail: MailConfig{
+			Driver:    "file",
+			From:      "no-reply@dbo.local",
+			OutboxDir: "outbox",
+			SMTPPort:  587,
+		},
+		Login: LoginConfig{
+			MaxFailures:     5,
+			IPMaxFailures:   20,
+			LockoutDuration: 15 * time.Minute,
+			DelayBase:       time.Second,
+		},
+		Password: PasswordConfig{
+			MinLength:     8,
+			MaxLength:     password.BcryptMaxLength,
+			CheckBreached: true,
+		},
+		Migrations: MigrationsConfig{
+			OnStart: true,
+			Dir:     "app/migrations/sql",
+		},
+		Metrics: MetricsConfig{
+			Path: "/metrics",
+		},
+	}
+}
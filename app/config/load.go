@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is one leaf of Config, e.g. db.host.
+type Setting struct {
+	Key    string
+	Env    string
+	Flag   string
+	Usage  string
+	Secret bool
+	value  reflect.Value
+}
+
+// String returns the current value as it would be written in a config file.
+func (s Setting) String() string {
+	if d, ok := s.value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return fmt.Sprint(s.value.Interface())
+}
+
+func (s Setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch s.value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration like 30s or 15m", s.Key, raw)
+		}
+		s.value.SetInt(int64(d))
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a whole number", s.Key, raw)
+		}
+		s.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not true or false", s.Key, raw)
+		}
+		s.value.SetBool(b)
+	default:
+		s.value.SetString(raw)
+	}
+	return nil
+}
+
+// Settings lists every setting of c in declaration order. The values are
+// live: reading them reflects later changes to c.
+func (c *Config) Settings() []Setting {
+	settings := []Setting{}
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i).Tag.Get("yaml")
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			field := fields.Type().Field(j)
+			key := section + "." + field.Tag.Get("yaml")
+			settings = append(settings, Setting{
+				Key:    key,
+				Env:    field.Tag.Get("env"),
+				Flag:   strings.NewReplacer(".", "-", "_", "-").Replace(key),
+				Usage:  field.Tag.Get("usage"),
+				Secret: field.Tag.Get("secret") == "true",
+				value:  fields.Field(j),
+			})
+		}
+	}
+	return settings
+}
+
+// RegisterFlags adds a flag for every setting to flags.
+func RegisterFlags(flags *pflag.FlagSet) {
+	for _, setting := range Default().Settings() {
+		usage := setting.Usage + " (" + setting.Env
+		if value := setting.String(); value != "" && value != "0" && value != "false" {
+			usage += ", default " + value
+		}
+		flags.String(setting.Flag, "", usage+")")
+	}
+}
+
+// Load builds the configuration from the defaults, then the optional file
+// (.yaml, .yml or .toml), then environment variables, then the flags that
+// were set on the command line. Empty environment variables are ignored. Every
+// problem found is reported together in a *ValidationError.
+func Load(file string, flags *pflag.FlagSet) (*Config, error) {
+	c := Default()
+	settings := c.Settings()
+	problems := []string{}
+
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		byKey := map[string]Setting{}
+		for _, setting := range settings {
+			byKey[setting.Key] = setting
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			setting, ok := byKey[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown setting %s", file, key))
+				continue
+			}
+			if err := setting.set(values[key]); err != nil {
+				problems = append(problems, file+": "+err.Error())
+			}
+		}
+	}
+
+	for _, setting := range settings {
+		if value := os.Getenv(setting.Env); value != "" {
+			if err := setting.set(value); err != nil {
+				problems = append(problems, setting.Env+": "+err.Error())
+			}
+		}
+	}
+
+	if flags != nil {
+		for _, setting := range settings {
+			flag := flags.Lookup(setting.Flag)
+			if flag == nil || !flag.Changed {
+				continue
+			}
+			if err := setting.set(flag.Value.String()); err != nil {
+				problems = append(problems, "--"+setting.Flag+": "+err.Error())
+			}
+		}
+	}
+
+	problems = append(problems, c.problems()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return c, nil
+}
+
+// readFile flattens a config file into dotted keys, e.g. db.host.
+func readFile(file string) (map[string]string, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, &tree)
+	case ".toml":
+		err = toml.Unmarshal(body, &tree)
+	default:
+		return nil, fmt.Errorf("%s: config files must end in .yaml, .yml or .toml", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	values := map[string]string{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			flatten(key, child, values)
+			continue
+		}
+		// Lists, e.g. http.trusted_proxies, are written comma-separated.
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+			continue
+		}
+		values[key] = fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+)
+
+// ValidationError lists everything wrong with a configuration at once, so it
+// can be fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate reports every problem with c in a *ValidationError, or nil.
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) problems() []string {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Address != "", "http.address is required")
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout can't be negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout can't be negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout can't be negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout can't be negative")
+	for _, proxy := range c.HTTP.TrustedProxyList() {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "http.trusted_proxies (HTTP_TRUSTED_PROXIES): %q is not an IP or CIDR", proxy)
+	}
+
+	check(oneOf(c.DB.Driver, "postgres", "mysql", "sqlite"), "db.driver (DB_DRIVER) must be postgres, mysql or sqlite, got %q", c.DB.Driver)
+	if c.DB.Driver != "sqlite" {
+		check(c.DB.Host != "", "db.host (DB_HOST) is required")
+		check(c.DB.Port > 0 && c.DB.Port < 65536, "db.port (DB_PORT) must be between 1 and 65535, got %d", c.DB.Port)
+		check(c.DB.Username != "", "db.username (DB_USERNAME) is required")
+	}
+	check(c.DB.Name != "", "db.name (DB_NAME) is required")
+	if c.DB.Driver == "postgres" {
+		check(oneOf(c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+			"db.sslmode (DB_SSLMODE) must be disable, allow, prefer, require, verify-ca or verify-full, got %q", c.DB.SSLMode)
+	}
+	_, err := time.LoadLocation(c.DB.TimeZone)
+	check(c.DB.TimeZone != "" && err == nil, "db.timezone (DB_TIMEZONE) %q is not a known time zone", c.DB.TimeZone)
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns (DB_MAX_OPEN_CONNS) can't be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns (DB_MAX_IDLE_CONNS) can't be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns can't be more than db.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime (DB_CONN_MAX_LIFETIME) can't be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME) can't be negative")
+	check(c.DB.ConnectTimeout >= 0, "db.connect_timeout (DB_CONNECT_TIMEOUT) can't be negative")
+
+	if c.JWT.KeysDir == "" {
+		check(c.JWT.SecretKey != "", "jwt.secret_key (SECRET_KEY) is required unless jwt.keys_dir is set")
+		check(c.JWT.SecretKey == "" || len(c.JWT.SecretKey) >= 32, "jwt.secret_key (SECRET_KEY) must be at least 32 bytes")
+	}
+	check(c.JWT.Issuer != "", "jwt.issuer is required")
+	check(c.JWT.Audience != "", "jwt.audience is required")
+	check(c.JWT.AccessTTL > 0, "jwt.access_ttl must be positive")
+	check(c.JWT.RefreshTTL > c.JWT.AccessTTL, "jwt.refresh_ttl must be longer than jwt.access_ttl")
+	check(oneOf(c.JWT.DenylistDriver, "memory", "database", "postgres"),
+		"jwt.denylist_driver (TOKEN_DENYLIST_DRIVER) must be memory or database, got %q", c.JWT.DenylistDriver)
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "text", "json"), "log.format must be text or json, got %q", c.Log.Format)
+
+	check(c.Auth.AppURL != "", "auth.app_url (APP_URL) is required")
+	_, ok := models.DefaultRoles[c.Auth.DefaultRole]
+	check(ok, "auth.default_role (DEFAULT_ROLE) %q is not a role", c.Auth.DefaultRole)
+
+	check(oneOf(c.Mail.Driver, "file", "db", "smtp"), "mail.driver (MAIL_DRIVER) must be file, db or smtp, got %q", c.Mail.Driver)
+	check(c.Mail.From != "", "mail.from (MAIL_FROM) is required")
+	if c.Mail.Driver == "file" {
+		check(c.Mail.OutboxDir != "", "mail.outbox_dir (MAIL_OUTBOX_DIR) is required for the file driver")
+	}
+	if c.Mail.Driver == "smtp" {
+		check(c.Mail.SMTPHost != "", "mail.smtp_host (SMTP_HOST) is required for the smtp driver")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort < 65536, "mail.smtp_port (SMTP_PORT) must be between 1 and 65535, got %d", c.Mail.SMTPPort)
+	}
+
+	check(c.Login.MaxFailures > 0, "login.max_failures (LOGIN_MAX_FAILURES) must be positive")
+	check(c.Login.IPMaxFailures > 0, "login.ip_max_failures (LOGIN_IP_MAX_FAILURES) must be positive")
+	check(c.Login.LockoutDuration > 0, "login.lockout_duration (LOGIN_LOCKOUT_DURATION) must be positive")
+	check(c.Login.DelayBase >= 0, "login.delay_base (LOGIN_DELAY_BASE) can't be negative")
+
+	check(c.Password.MinLength > 0, "password.min_length (PASSWORD_MIN_LENGTH) must be positive")
+	check(c.Password.MaxLength <= password.BcryptMaxLength, "password.max_length (PASSWORD_MAX_LENGTH) can't be over %d, bcrypt ignores the rest", password.BcryptMaxLength)
+	check(c.Password.MinLength <= c.Password.MaxLength, "password.min_length can't be more than password.max_length")
+
+	if c.Metrics.Enabled {
+		check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path (METRICS_PATH) must start with /, got %q", c.Metrics.Path)
+		check(c.Metrics.Address == "" || c.Metrics.Address != c.HTTP.Address,
+			"metrics.address (METRICS_ADDRESS) must differ from http.address, leave it empty to serve metrics with the API")
+	}
+
+	return problems
+}
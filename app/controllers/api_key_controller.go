@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix = "dbo_"
+	// apiKeyPrefixLength is how much of a key is kept in the clear: "dbo_"
+	// followed by 8 hex characters.
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval limits how often last_used_at is written for a
+	// busy key.
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	errAPIKeyInvalid = &TokenError{Code: "api_key_invalid", Message: "Invalid API key"}
+	errAPIKeyExpired = &TokenError{Code: "api_key_expired", Message: "API key has expired"}
+	errAPIKeyRevoked = &TokenError{Code: "api_key_revoked", Message: "API key has been revoked"}
+
+	errAPIKeyNotFound = &service.Error{Message: "API key not found", NotFound: true}
+)
+
+type APIKeyRepo struct {
+	DB *gorm.DB
+}
+
+type ReqAPIKey struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ReqAPIKeyName struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func APIKeyController(db *gorm.DB) *APIKeyRepo {
+	return &APIKeyRepo{DB: db}
+}
+
+func newAPIKey() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(b)
+}
+
+// ValidateAPIKey resolves an API key to its owner. The key comes with its
+// scopes and the user with roles and permissions; a request may only use a
+// permission both of them grant.
+func ValidateAPIKey(key string, ip string, db *gorm.DB) (*models.User, *models.APIKey, error) {
+	var apiKey models.APIKey
+	result := db.Preload("Scopes").Preload("User.Roles.Permissions").Where("key_hash = ?", hashToken(key)).First(&apiKey)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil, errAPIKeyInvalid
+		}
+		return nil, nil, result.Error
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return nil, nil, errAPIKeyRevoked
+	}
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return nil, nil, errAPIKeyExpired
+	}
+	if apiKey.User.DisabledAt != nil {
+		return nil, nil, ErrUserDisabled
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval || apiKey.LastUsedIP != ip {
+		err := db.Model(&apiKey).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+		if err != nil {
+			slog.ErrorContext(db.Statement.Context, "recording API key use", slog.Any("error", err))
+		}
+	}
+
+	return &apiKey.User, &apiKey, nil
+}
+
+func (repo *APIKeyRepo) GetAllAPIKeys(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	user := c.MustGet("user").(*models.User)
+
+	var apiKeys []models.APIKey
+	result := requestDB(c, repo.DB).Preload("Scopes").Where("user_id = ?", user.ID).Order("id").Find(&apiKeys)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = apiKeys
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateAPIKey issues a key limited to scopes, which must be permissions the
+// user holds. The key itself is only part of this response.
+func (repo *APIKeyRepo) CreateAPIKey(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqAPIKey{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	for _, scope := range req.Scopes {
+		if !user.HasPermission(scope) {
+			errorMsg := "Scope not granted to user: " + scope
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		errorMsg := "expires_at must be in the future"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var scopes []models.Permission
+	result := requestDB(c, repo.DB).Where("name IN ?", req.Scopes).Find(&scopes)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	key := newAPIKey()
+	apiKey := models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	result = requestDB(c, repo.DB).Create(&apiKey)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (repo *APIKeyRepo) UpdateAPIKey(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqAPIKeyName{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	apiKeyID, ok := idParam(c, errAPIKeyNotFound)
+	if !ok {
+		return
+	}
+
+	var apiKey models.APIKey
+	result := requestDB(c, repo.DB).Preload("Scopes").Where("user_id = ?", user.ID).First(&apiKey, apiKeyID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "API key not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	result = requestDB(c, repo.DB).Model(&apiKey).Update("name", req.Name)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = apiKey
+	c.JSON(http.StatusOK, res)
+}
+
+// RevokeAPIKey disables a key for good. The record is kept so its last use
+// can still be looked up.
+func (repo *APIKeyRepo) RevokeAPIKey(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user := c.MustGet("user").(*models.User)
+	apiKeyID, ok := idParam(c, errAPIKeyNotFound)
+	if !ok {
+		return
+	}
+
+	var apiKey models.APIKey
+	result := requestDB(c, repo.DB).Where("user_id = ?", user.ID).First(&apiKey, apiKeyID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "API key not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		result = requestDB(c, repo.DB).Model(&apiKey).Update("revoked_at", time.Now())
+		if result.Error != nil {
+			errorMsg := result.Error.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			return
+		}
+	}
+
+	res.Data = "API key revoked successfully"
+	c.JSON(http.StatusOK, res)
+}
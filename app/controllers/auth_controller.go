@@ -1,15 +1,16 @@
 package controllers
 
 import (
-	"errors"
+	"io"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/fajaaro/dbo/app"
+	"github.com/fajaaro/dbo/app/denylist"
+	"github.com/fajaaro/dbo/app/metrics"
 	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+	"github.com/fajaaro/dbo/app/store"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gin-gonic/gin"
@@ -17,63 +18,131 @@ import (
 )
 
 type AuthRepo struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Users *service.UserService
 }
 
+// ReqAuth only requires a password; the rules for new passwords are checked
+// by validatePassword.
 type ReqAuth struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
 }
 
-func AuthController() *AuthRepo {
-	return &AuthRepo{DB: app.InitDb()}
+type ReqRegister struct {
+	ReqAuth
+	Organization string `json:"organization"`
 }
 
-func ValidateAccessToken(accessToken string, db *gorm.DB) (*models.User, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		return SECRET_KEY, nil
-	})
+func AuthController(db *gorm.DB) *AuthRepo {
+	users := service.NewUserService(store.NewGormUserStore(db), passwordPolicy)
+	return &AuthRepo{DB: db, Users: users}
+}
+
+// TokenDenylist holds the jti of access tokens revoked by logout. main swaps
+// in the backend selected by TOKEN_DENYLIST_DRIVER.
+var TokenDenylist denylist.Store = denylist.NewMemoryStore(time.Minute)
+
+func ValidateAccessToken(accessToken string, db *gorm.DB) (*models.User, *TokenClaims, error) {
+	claims, err := parseToken(accessToken, TokenTypeAccess)
 	if err != nil {
-		return nil, errors.New("Invalid access token")
+		return nil, nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return nil, errors.New("Invalid access token")
+	revoked, err := TokenDenylist.Contains(claims.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrTokenRevoked
 	}
 
-	userEmail, ok := claims["sub"].(string)
+	userID, ok := subjectUserID(claims.Subject)
 	if !ok {
-		return nil, errors.New("Invalid user email in token claims")
+		return nil, nil, ErrTokenUnknownUser
 	}
-
 	var user models.User
-	result := db.Where("email = ?", userEmail).First(&user)
+	result := db.Preload("Roles.Permissions").First(&user, userID)
 	if result.Error != nil {
-		return nil, result.Error
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil, ErrTokenUnknownUser
+		}
+		return nil, nil, result.Error
 	}
 
-	return &user, nil
-}
+	if user.DisabledAt != nil {
+		return nil, nil, ErrUserDisabled
+	}
 
-var SECRET_KEY = []byte(os.Getenv("SECRET_KEY"))
+	if user.TokensRevokedAt != nil && claims.IssuedAt < user.TokensRevokedAt.Unix() {
+		return nil, nil, ErrTokenRevoked
+	}
 
-func createToken(tokenType string, exp time.Time, email string) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"token_type": tokenType,
-		"exp":        exp.Unix(),
-		"sub":        email,
-	})
+	if claims.SessionID != "" {
+		var revokedSessions int64
+		result = db.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NOT NULL", claims.SessionID).
+			Count(&revokedSessions)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		if revokedSessions > 0 {
+			return nil, nil, ErrTokenRevoked
+		}
+	}
 
-	tokenStr, _ := token.SignedString(SECRET_KEY)
+	// An impersonation token dies with the admin's right to impersonate.
+	if claims.Actor != nil {
+		actorID, ok := subjectUserID(claims.Actor.Subject)
+		if !ok {
+			return nil, nil, ErrTokenRevoked
+		}
+		var actor models.User
+		result = db.Preload("Roles.Permissions").First(&actor, actorID)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			return nil, nil, result.Error
+		}
+		if result.Error != nil || actor.DisabledAt != nil || actor.TenantID != user.TenantID || !actor.HasPermission(models.PermissionUsersManage) {
+			return nil, nil, ErrTokenRevoked
+		}
+	}
 
-	return tokenStr
+	// Deleting an OAuth client revokes the tokens it was given.
+	if claims.ClientID != "" {
+		var clients int64
+		result = db.Model(&models.OAuthClient{}).Where("client_id = ?", claims.ClientID).Count(&clients)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		if clients == 0 {
+			return nil, nil, ErrTokenRevoked
+		}
+	}
+
+	return &user, claims, nil
+}
+
+// tokenErrorStatus maps token validation failures to 401 and anything else
+// (database errors) to 500.
+func tokenErrorStatus(err error) int {
+	if _, ok := err.(*TokenError); ok {
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// tokenErrorCode returns the code of a TokenError for JsonResponse.Code.
+func tokenErrorCode(err error) *string {
+	if tokenErr, ok := err.(*TokenError); ok {
+		return &tokenErr.Code
+	}
+	return nil
 }
 
 func (repo *AuthRepo) Register(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
-	req := ReqAuth{}
+	req := ReqRegister{}
 	err := c.BindJSON(&req)
 	if err != nil {
 		validationErrors := err.(validator.ValidationErrors)
@@ -86,44 +155,25 @@ func (repo *AuthRepo) Register(c *gin.Context) {
 		return
 	}
 
-	var count int64
-	repo.DB.Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
-	if count > 0 {
-		errorMsg := "Email already exists"
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	// Registering starts a new tenant and makes the user its admin.
+	// Colleagues are added to it through CreateTenantUser.
+	user, err := repo.Users.Register(c.Request.Context(), service.RegisterInput{
+		Email:        req.Email,
+		Password:     req.Password,
+		Organization: req.Organization,
+	})
 	if err != nil {
-		res.Success = false
-		errorMsg := "Failed to hash password"
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
-		c.Abort()
+		respondServiceError(err, c)
 		return
 	}
 
-	user := &models.User{
-		Email:    req.Email,
-		Password: string(hashedPassword),
-	}
-	result := repo.DB.Create(&user)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
-		c.Abort()
-		return
-	}
+	sendVerificationEmailAfterRegister(requestDB(c, repo.DB), *user)
 
 	res.Data = map[string]interface{}{
-		"user_id": user.ID,
-		"email":   user.Email,
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"email":     user.Email,
+		"roles":     user.RoleNames(),
 	}
 	c.JSON(http.StatusCreated, res)
 }
@@ -144,9 +194,32 @@ func (repo *AuthRepo) Login(c *gin.Context) {
 		return
 	}
 
+	email := normalizeEmail(req.Email)
+	throttle := loginThrottle()
+	err = checkLoginThrottle(requestDB(c, repo.DB), throttle, email, c.ClientIP())
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		if throttled, ok := err.(*loginThrottledError); ok {
+			metrics.LoginFailures.WithLabelValues(metrics.LoginThrottled).Inc()
+			errorCode := throttled.Code()
+			res.Code = &errorCode
+			c.Header("Retry-After", throttled.RetryAfterSeconds())
+			c.JSON(http.StatusTooManyRequests, res)
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
 	var user models.User
-	result := repo.DB.Where("email = ?", req.Email).First(&user)
+	result := requestDB(c, repo.DB).Preload("Roles").Where("LOWER(email) = ?", email).First(&user)
 	if result.Error != nil {
+		repo.recordLoginFailure(throttle, email, c)
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
 		errorMsg := "Invalid credentials"
 		res.Success = false
 		res.Error = &errorMsg
@@ -157,6 +230,8 @@ func (repo *AuthRepo) Login(c *gin.Context) {
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		repo.recordLoginFailure(throttle, email, c)
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials).Inc()
 		errorMsg := "Invalid credentials"
 		res.Success = false
 		res.Error = &errorMsg
@@ -165,20 +240,128 @@ func (repo *AuthRepo) Login(c *gin.Context) {
 		return
 	}
 
-	accessTokenExp := time.Now().Add(15 * time.Minute)
-	accessToken := createToken("access_token", accessTokenExp, user.Email)
+	if user.DisabledAt != nil {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginDisabled).Inc()
+		errorMsg := ErrUserDisabled.Message
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &ErrUserDisabled.Code
+		c.JSON(http.StatusForbidden, res)
+		c.Abort()
+		return
+	}
 
-	refreshTokenExp := time.Now().Add(30 * 24 * time.Hour)
-	refreshToken := createToken("refresh_token", refreshTokenExp, user.Email)
+	if user.PasswordResetRequired {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginPasswordResetRequired).Inc()
+		errorMsg := "Password reset required"
+		errorCode := "password_reset_required"
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &errorCode
+		c.JSON(http.StatusForbidden, res)
+		c.Abort()
+		return
+	}
 
-	data := gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+	if requireEmailVerification() && user.EmailVerifiedAt == nil {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginEmailNotVerified).Inc()
+		errorMsg := "Email not verified"
+		errorCode := "email_not_verified"
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &errorCode
+		c.JSON(http.StatusForbidden, res)
+		c.Abort()
+		return
 	}
+
+	err = resetLoginFailures(requestDB(c, repo.DB), user.Email)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
+	// With two-factor authentication the password alone only earns a
+	// short-lived token for /api/auth/mfa/verify.
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := issueUserToken(requestDB(c, repo.DB), user, TokenTypeMFAPending, mfaPendingTTL)
+		if err != nil {
+			errorMsg := err.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			c.Abort()
+			return
+		}
+
+		res.Data = gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		}
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	data, err := issueLoginTokens(requestDB(c, repo.DB), user, c)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+	metrics.LoginSuccesses.Inc()
+
 	res.Data = data
 	c.JSON(http.StatusOK, res)
 }
 
+// issueLoginTokens starts a new session for user with a fresh access and
+// refresh token pair.
+func issueLoginTokens(db *gorm.DB, user models.User, c *gin.Context) (gin.H, error) {
+	session := models.Session{
+		UserID:    user.ID,
+		FamilyID:  newRandomID(),
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := createSessionAccessToken(user, session.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, _, err := issueRefreshToken(db, user, session.FamilyID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	}, nil
+}
+
+// countRefresh counts a refresh token exchange that ended with err: success,
+// the code of a *TokenError, or error.
+func countRefresh(err error) {
+	result := "success"
+	if code := tokenErrorCode(err); code != nil {
+		result = *code
+	} else if err != nil {
+		result = "error"
+	}
+	metrics.TokenRefreshes.WithLabelValues(result).Inc()
+}
+
 func (repo *AuthRepo) RefreshToken(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
@@ -195,40 +378,89 @@ func (repo *AuthRepo) RefreshToken(c *gin.Context) {
 
 	refreshToken := req["refresh_token"]
 
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		return SECRET_KEY, nil
-	})
+	_, err = parseToken(refreshToken, TokenTypeRefresh)
 	if err != nil {
-		errorMsg := "Invalid refresh token"
+		countRefresh(err)
+		errorMsg := err.Error()
 		res.Success = false
 		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
 		c.JSON(http.StatusUnauthorized, res)
 		c.Abort()
 		return
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		errorMsg := "Expired refresh token"
+	stored, newRefreshToken, err := rotateRefreshToken(requestDB(c, repo.DB), refreshToken, c)
+	countRefresh(err)
+	if err != nil {
+		errorMsg := err.Error()
 		res.Success = false
 		res.Error = &errorMsg
-		c.JSON(http.StatusUnauthorized, res)
+		res.Code = tokenErrorCode(err)
+		c.JSON(tokenErrorStatus(err), res)
 		c.Abort()
 		return
 	}
 
-	userEmail := claims["sub"].(string)
-
-	accessTokenExp := time.Now().Add(15 * time.Minute)
-	accessToken := createToken("access_token", accessTokenExp, userEmail)
+	accessToken, err := createSessionAccessToken(stored.User, stored.FamilyID)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
 
 	data := gin.H{
-		"access_token": accessToken,
+		"access_token":  accessToken,
+		"refresh_token": newRefreshToken,
 	}
 	res.Data = data
 	c.JSON(http.StatusOK, res)
 }
 
+// RevokeToken kills the session a refresh token belongs to. Unknown tokens are
+// reported as revoked too, so the endpoint can't be used to probe tokens.
+func (repo *AuthRepo) RevokeToken(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := map[string]string{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		c.Abort()
+		return
+	}
+
+	var stored models.RefreshToken
+	result := requestDB(c, repo.DB).Where("token_hash = ?", hashToken(req["refresh_token"])).First(&stored)
+	if result.Error == nil {
+		err = revokeRefreshTokenFamily(requestDB(c, repo.DB), stored.FamilyID)
+		if err != nil {
+			errorMsg := err.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			c.Abort()
+			return
+		}
+	} else if result.Error != gorm.ErrRecordNotFound {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
+	res.Data = "Token revoked successfully"
+	c.JSON(http.StatusOK, res)
+}
+
 func (repo *AuthRepo) MatchToken(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
@@ -245,13 +477,14 @@ func (repo *AuthRepo) MatchToken(c *gin.Context) {
 
 	accessToken := req["access_token"]
 
-	user, err := ValidateAccessToken(accessToken, repo.DB)
+	user, _, err := ValidateAccessToken(accessToken, requestDB(c, repo.DB))
 	if err != nil {
 		errorMsg := err.Error()
 		res.Success = false
 		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
 
-		c.JSON(http.StatusUnauthorized, res)
+		c.JSON(tokenErrorStatus(err), res)
 		c.Abort()
 		return
 	}
@@ -262,3 +495,100 @@ func (repo *AuthRepo) MatchToken(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, res)
 }
+
+// revokeAccessToken denylists the access token the request was authenticated
+// with until it would have expired anyway.
+func revokeAccessToken(c *gin.Context) error {
+	claims := c.MustGet("claims").(*TokenClaims)
+	return TokenDenylist.Add(claims.Id, time.Unix(claims.ExpiresAt, 0))
+}
+
+// Logout ends the current session: the access token is denylisted and the
+// session is revoked, as is the refresh token's family when one is given.
+func (repo *AuthRepo) Logout(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := map[string]string{}
+	err := c.ShouldBindJSON(&req)
+	if err != nil && err != io.EOF {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		c.Abort()
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	if refreshToken := req["refresh_token"]; refreshToken != "" {
+		var stored models.RefreshToken
+		result := requestDB(c, repo.DB).Where("token_hash = ? AND user_id = ?", hashToken(refreshToken), user.ID).First(&stored)
+		if result.Error == nil {
+			err = revokeRefreshTokenFamily(requestDB(c, repo.DB), stored.FamilyID)
+		} else if result.Error != gorm.ErrRecordNotFound {
+			err = result.Error
+		}
+		if err != nil {
+			errorMsg := err.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			c.Abort()
+			return
+		}
+	}
+
+	if claims := c.MustGet("claims").(*TokenClaims); claims.SessionID != "" {
+		err = revokeRefreshTokenFamily(requestDB(c, repo.DB), claims.SessionID)
+	}
+	if err == nil {
+		err = revokeAccessToken(c)
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
+	res.Data = "Logged out successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// LogoutAll ends every session of the user: all refresh tokens are revoked and
+// access tokens issued before now are rejected by ValidateAccessToken.
+func (repo *AuthRepo) LogoutAll(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user := c.MustGet("user").(*models.User)
+
+	err := requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		return revokeAllSessions(tx, user)
+	})
+	if err == nil {
+		err = revokeAccessToken(c)
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
+	res.Data = "Logged out from all sessions successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// JWKS publishes the public signing keys at /.well-known/jwks.json in plain
+// RFC 7517 format, without the JsonResponse envelope, so standard JWT
+// libraries can consume it.
+func (repo *AuthRepo) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, SigningKeys.JWKS())
+}
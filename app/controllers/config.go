@@ -0,0 +1,9 @@
+package controllers
+
+import (
+	"github.com/fajaaro/dbo/app/config"
+)
+
+// Config holds the settings the handlers read. It starts out as the defaults
+// and is replaced with the loaded configuration at startup.
+var Config = config.Default()
@@ -3,10 +3,11 @@ package controllers
 import (
 	"net/http"
 	"strconv"
-	"strings"
 
-	"github.com/fajaaro/dbo/app"
 	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+	"github.com/fajaaro/dbo/app/service"
+	"github.com/fajaaro/dbo/app/store"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,8 @@ import (
 )
 
 type CustomerRepo struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Customers *service.CustomerService
 }
 
 type ReqCustomer struct {
@@ -30,38 +32,71 @@ type ReqUpdateCustomer struct {
 	Gender      string `json:"gender" binding:"required"`
 }
 
-func CustomerController() *CustomerRepo {
-	return &CustomerRepo{DB: app.InitDb()}
+func CustomerController(db *gorm.DB) *CustomerRepo {
+	customers := service.NewCustomerService(store.NewGormCustomerStore(db), store.NewGormOrderStore(db))
+	return &CustomerRepo{DB: db, Customers: customers}
 }
 
-func (repo *CustomerRepo) GetAllCustomers(c *gin.Context) {
-	c.Header("Content-Type", "application/json")
-	res := models.JsonResponse{Success: true}
+// respondServiceError answers err from a service: 404 or 400 with the
+// message of a *service.Error, 400 with the code of a rejected password, and
+// 500 for anything else.
+func respondServiceError(err error, c *gin.Context) {
+	errorMsg := err.Error()
+	res := models.JsonResponse{
+		Success: false,
+		Error:   &errorMsg,
+	}
+
+	status := http.StatusInternalServerError
+	switch e := err.(type) {
+	case *service.Error:
+		status = http.StatusBadRequest
+		if e.NotFound {
+			status = http.StatusNotFound
+		}
+	case *password.PolicyError:
+		status = http.StatusBadRequest
+		res.Code = &e.Code
+	}
+	c.JSON(status, res)
+	c.Abort()
+}
 
-	page := c.DefaultQuery("page", "1")
-	pageNum, _ := strconv.Atoi(page)
-	limit := c.DefaultQuery("limit", "10")
-	limitNum, _ := strconv.Atoi(limit)
-	search := c.DefaultQuery("search", "")
-	search = strings.ToLower(search)
+// pageQuery reads the page, limit and search query parameters of a listing.
+func pageQuery(c *gin.Context) store.Page {
+	pageNum, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limitNum, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	return store.Page{
+		Search: c.DefaultQuery("search", ""),
+		Offset: (pageNum - 1) * limitNum,
+		Limit:  limitNum,
+	}
+}
 
-	var customers []models.Customer
-	query := repo.DB.Model(&models.Customer{})
+// idParam reads the :id route parameter. Anything that isn't an ID is
+// answered with notFound.
+func idParam(c *gin.Context, notFound error) (uint, bool) {
+	return uintParam(c, "id", notFound)
+}
 
-	if search != "" {
-		query = query.Where("name ILIKE ? OR email ILIKE ? OR phone_number ILIKE ?", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+// uintParam reads the route parameter name as an ID, answering notFound when
+// it isn't one.
+func uintParam(c *gin.Context, name string, notFound error) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		respondServiceError(notFound, c)
+		return 0, false
 	}
+	return uint(id), true
+}
 
-	var count int64
-	query.Count(&count)
-
-	query = query.Offset((pageNum - 1) * limitNum).Limit(limitNum).Find(&customers)
+func (repo *CustomerRepo) GetAllCustomers(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
 
-	if query.Error != nil {
-		errorMsg := query.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	customers, count, err := repo.Customers.List(c.Request.Context(), tenantID(c), pageQuery(c))
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -76,22 +111,14 @@ func (repo *CustomerRepo) GetCustomerDetail(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	customerID := c.Param("id")
-
-	var customer models.Customer
-	result := repo.DB.First(&customer, customerID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Customer not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	customerID, ok := idParam(c, service.ErrCustomerNotFound)
+	if !ok {
+		return
+	}
+
+	customer, err := repo.Customers.Get(c.Request.Context(), tenantID(c), customerID)
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -115,39 +142,14 @@ func (repo *CustomerRepo) InsertCustomer(c *gin.Context) {
 		return
 	}
 
-	req.Gender = strings.ToLower(req.Gender)
-	if req.Gender != "male" && req.Gender != "female" {
-		errorMsg := "Invalid gender"
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	var count int64
-	repo.DB.Model(&models.Customer{}).Where("email = ?", req.Email).Count(&count)
-	if count > 0 {
-		errorMsg := "Email already exists"
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	customer := &models.Customer{
+	customer, err := repo.Customers.Create(c.Request.Context(), tenantID(c), service.CustomerInput{
 		Name:        req.Name,
 		Email:       req.Email,
 		PhoneNumber: req.PhoneNumber,
 		Gender:      req.Gender,
-	}
-	result := repo.DB.Create(&customer)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	})
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -171,48 +173,18 @@ func (repo *CustomerRepo) UpdateCustomer(c *gin.Context) {
 		return
 	}
 
-	req.Gender = strings.ToLower(req.Gender)
-	if req.Gender != "male" && req.Gender != "female" {
-		errorMsg := "Invalid gender"
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	// Get Customer ID from URL parameter
-	customerID := c.Param("id")
-
-	// Query Customer by ID
-	var customer models.Customer
-	result := repo.DB.First(&customer, customerID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Customer not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	customerID, ok := idParam(c, service.ErrCustomerNotFound)
+	if !ok {
 		return
 	}
 
-	// Update Customer fields
-	customer.Name = req.Name
-	customer.PhoneNumber = req.PhoneNumber
-	customer.Gender = req.Gender
-
-	result = repo.DB.Save(&customer)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	customer, err := repo.Customers.Update(c.Request.Context(), tenantID(c), customerID, service.CustomerInput{
+		Name:        req.Name,
+		PhoneNumber: req.PhoneNumber,
+		Gender:      req.Gender,
+	})
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -224,42 +196,15 @@ func (repo *CustomerRepo) DeleteCustomer(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	customerID := c.Param("id")
-
-	var customer models.Customer
-	result := repo.DB.First(&customer, customerID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Customer not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	customerID, ok := idParam(c, service.ErrCustomerNotFound)
+	if !ok {
 		return
 	}
 
-	// Delete customer's orders
-	result = repo.DB.Where("customer_id = ?", customerID).Delete(&models.Order{})
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
-		return
-	}
-
-	// Delete customer
-	result = repo.DB.Delete(&customer)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	// Deleting a customer also deletes their orders.
+	err := repo.Customers.Delete(c.Request.Context(), tenantID(c), customerID)
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/fajaaro/dbo/app/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit stores an audit record for the current request, done by
+// actorEmail as user. Failing to write it is logged, not returned, so it
+// can't fail a request that already happened.
+func recordAudit(db *gorm.DB, c *gin.Context, action string, actorEmail string, user *models.User) {
+	record := models.AuditLog{
+		TenantID:   user.TenantID,
+		ActorEmail: actorEmail,
+		UserID:     user.ID,
+		Action:     action,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     c.Writer.Status(),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "writing audit log", slog.Any("error", err))
+	}
+}
+
+// RecordImpersonatedRequest audits a request made with an impersonation
+// token. middlewares.JWT() calls it once the handler has run.
+func RecordImpersonatedRequest(db *gorm.DB, c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	recordAudit(db, c, models.AuditImpersonatedRequest, c.GetString("actor"), user)
+}
+
+// Impersonate issues a short-lived access token for a user of the tenant so
+// support can see the API as they do. The token names the admin in its act
+// claim, can't be refreshed and can't reach endpoints guarded by
+// middlewares.RequireAccessToken.
+func (repo *UserRepo) Impersonate(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	admin := c.MustGet("user").(*models.User)
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok || isCurrentUser(user, c) {
+		return
+	}
+
+	if user.DisabledAt != nil {
+		errorMsg := ErrUserDisabled.Message
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &ErrUserDisabled.Code
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	err := requestDB(c, repo.DB).Model(user).Association("Roles").Find(&user.Roles)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	accessToken, err := createImpersonationToken(*user, *admin)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "impersonation started",
+		slog.String("actor", admin.Email),
+		slog.String("user", user.Email),
+	)
+
+	res.Data = gin.H{
+		"access_token": accessToken,
+		"expires_in":   int(impersonationTokenTTL.Seconds()),
+	}
+	c.JSON(http.StatusOK, res)
+
+	recordAudit(requestDB(c, repo.DB), c, models.AuditImpersonationStart, admin.Email, user)
+}
+
+// GetAuditLogs lists the tenant's audit records, newest first. actor and
+// action narrow it down.
+func (repo *UserRepo) GetAuditLogs(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	page := c.DefaultQuery("page", "1")
+	pageNum, _ := strconv.Atoi(page)
+	limit := c.DefaultQuery("limit", "10")
+	limitNum, _ := strconv.Atoi(limit)
+
+	var auditLogs []models.AuditLog
+	query := requestDB(c, repo.DB).Scopes(tenantScope(c)).Model(&models.AuditLog{})
+
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor_email = ?", actor)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	query = query.Order("id DESC").Offset((pageNum - 1) * limitNum).Limit(limitNum).Find(&auditLogs)
+	if query.Error != nil {
+		errorMsg := query.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = map[string]interface{}{
+		"audit_logs": auditLogs,
+		"count":      count,
+	}
+	c.JSON(http.StatusOK, res)
+}
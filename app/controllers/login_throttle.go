@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginThrottle holds the brute-force protection thresholds. Every failed
+// login delays the next attempt for the same email by DelayBase, doubled per
+// failure. MaxFailures failures for an email, or IPMaxFailures for a client
+// IP, lock it for Lockout. IPs only get the lockout: many users may share one
+// behind a NAT. Counters reset once Lockout has passed without a new failure.
+type LoginThrottle struct {
+	MaxFailures   int
+	IPMaxFailures int
+	Lockout       time.Duration
+	DelayBase     time.Duration
+}
+
+type loginThrottledError struct {
+	locked     bool
+	retryAfter time.Duration
+}
+
+func (e *loginThrottledError) Error() string {
+	if e.locked {
+		return "Account temporarily locked due to too many failed login attempts"
+	}
+	return "Too many failed login attempts, try again later"
+}
+
+func (e *loginThrottledError) Code() string {
+	if e.locked {
+		return "account_locked"
+	}
+	return "login_throttled"
+}
+
+// RetryAfterSeconds is the value of the Retry-After header.
+func (e *loginThrottledError) RetryAfterSeconds() string {
+	return strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds())))
+}
+
+func loginThrottle() LoginThrottle {
+	return LoginThrottle{
+		MaxFailures:   Config.Login.MaxFailures,
+		IPMaxFailures: Config.Login.IPMaxFailures,
+		Lockout:       Config.Login.LockoutDuration,
+		DelayBase:     Config.Login.DelayBase,
+	}
+}
+
+// delay is how long to wait after the given number of consecutive failures.
+func (t LoginThrottle) delay(failures int) time.Duration {
+	if failures <= 0 || t.DelayBase <= 0 {
+		return 0
+	}
+	d := t.DelayBase << uint(failures-1)
+	if d <= 0 || d > t.Lockout {
+		return t.Lockout
+	}
+	return d
+}
+
+// normalizeEmail is the form of an email logins are looked up and throttled
+// by, so every spelling of an address reaches the same account and counter.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginAttemptIdentifiers(email string, ip string) map[string]string {
+	return map[string]string{
+		models.LoginAttemptEmail: normalizeEmail(email),
+		models.LoginAttemptIP:    ip,
+	}
+}
+
+// checkLoginThrottle returns a *loginThrottledError when the email or the IP
+// may not attempt a login right now.
+func checkLoginThrottle(db *gorm.DB, throttle LoginThrottle, email string, ip string) error {
+	now := time.Now()
+	var worst *loginThrottledError
+
+	for scope, identifier := range loginAttemptIdentifiers(email, ip) {
+		var attempt models.LoginAttempt
+		result := db.Where("scope = ? AND identifier = ?", scope, identifier).First(&attempt)
+		if result.Error == gorm.ErrRecordNotFound {
+			continue
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var throttled *loginThrottledError
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			throttled = &loginThrottledError{locked: true, retryAfter: attempt.LockedUntil.Sub(now)}
+		} else if attempt.LockedUntil == nil && scope == models.LoginAttemptEmail {
+			nextAttempt := attempt.LastFailureAt.Add(throttle.delay(attempt.Failures))
+			if nextAttempt.After(now) {
+				throttled = &loginThrottledError{retryAfter: nextAttempt.Sub(now)}
+			}
+		}
+		if throttled != nil && (worst == nil || throttled.retryAfter > worst.retryAfter) {
+			worst = throttled
+		}
+	}
+
+	if worst != nil {
+		return worst
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login for the email and the IP, locking
+// either once it reaches its threshold. Unknown emails are counted too so the
+// response doesn't reveal which accounts exist.
+func recordLoginFailure(db *gorm.DB, throttle LoginThrottle, email string, ip string) error {
+	now := time.Now()
+	// A counter whose last failure or lock is older than the lockout starts over.
+	stale := "login_attempts.last_failure_at < ? OR (login_attempts.locked_until IS NOT NULL AND login_attempts.locked_until <= ?)"
+	staleBefore := now.Add(-throttle.Lockout)
+
+	for scope, identifier := range loginAttemptIdentifiers(email, ip) {
+		attempt := models.LoginAttempt{Scope: scope, Identifier: identifier, Failures: 1, LastFailureAt: now}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "identifier"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN "+stale+" THEN 1 ELSE login_attempts.failures + 1 END", staleBefore, now),
+				"locked_until":    gorm.Expr("CASE WHEN "+stale+" THEN NULL ELSE login_attempts.locked_until END", staleBefore, now),
+				"last_failure_at": now,
+				"updated_at":      now,
+			}),
+		}).Create(&attempt).Error
+		if err != nil {
+			return err
+		}
+
+		maxFailures := throttle.MaxFailures
+		if scope == models.LoginAttemptIP {
+			maxFailures = throttle.IPMaxFailures
+		}
+		err = db.Model(&models.LoginAttempt{}).
+			Where("scope = ? AND identifier = ? AND failures >= ? AND locked_until IS NULL", scope, identifier, maxFailures).
+			Update("locked_until", now.Add(throttle.Lockout)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetLoginFailures clears the email's counter after a successful login. The
+// IP counter is left to expire, otherwise an attacker holding one valid
+// account could reset it between guesses.
+func resetLoginFailures(db *gorm.DB, email string) error {
+	return db.Where("scope = ? AND identifier = ?", models.LoginAttemptEmail, normalizeEmail(email)).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+// recordLoginFailure is best effort: failing to count an attempt must not turn
+// a wrong password into a 500.
+func (repo *AuthRepo) recordLoginFailure(throttle LoginThrottle, email string, c *gin.Context) {
+	if err := recordLoginFailure(requestDB(c, repo.DB), throttle, email, c.ClientIP()); err != nil {
+		slog.ErrorContext(c.Request.Context(), "recording failed login", slog.Any("error", err))
+	}
+}
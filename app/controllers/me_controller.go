@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReqUpdateMe only changes the fields that are sent. Changing the email needs
+// the current password.
+type ReqUpdateMe struct {
+	Name            *string `json:"name"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ReqChangePassword struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// revokeOtherSessions ends every session of user except the one of
+// familyID, which the request came from.
+func revokeOtherSessions(db *gorm.DB, user *models.User, familyID string) error {
+	now := time.Now()
+	err := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", user.ID, familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", user.ID, familyID).
+		Update("revoked_at", now).Error
+}
+
+// GetMe returns the user the request is authenticated as, with roles and
+// permissions.
+func (repo *AuthRepo) GetMe(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	user := c.MustGet("user").(*models.User)
+
+	res.Data = user
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateMe edits the profile of the current user. A new email has to be
+// verified again. As the email is what the user logs in with and where
+// password resets go, changing it is treated like changing the password:
+// every session ends and the response carries a fresh token pair for this one.
+func (repo *AuthRepo) UpdateMe(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqUpdateMe{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	if emailChanged {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword))
+		if err != nil {
+			errorMsg := "Invalid current password"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+
+		var count int64
+		result := requestDB(c, repo.DB).Model(&models.User{}).Where("email = ?", *req.Email).Count(&count)
+		if result.Error != nil {
+			errorMsg := result.Error.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			return
+		}
+		if count > 0 {
+			errorMsg := "Email already exists"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+
+		updates["email"] = *req.Email
+		updates["email_verified_at"] = nil
+	}
+
+	var tokens gin.H
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(user).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if !emailChanged {
+			return nil
+		}
+
+		if err := invalidateMailedTokens(tx, user); err != nil {
+			return err
+		}
+		if err := revokeAllSessions(tx, user); err != nil {
+			return err
+		}
+		var err error
+		tokens, err = issueLoginTokens(tx, *user, c)
+		return err
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	if emailChanged {
+		// Like after Register, a mail failure doesn't undo the change.
+		if err := sendVerificationEmail(requestDB(c, repo.DB), *user); err != nil {
+			slog.ErrorContext(c.Request.Context(), "sending verification email", slog.Any("error", err))
+		}
+		if err := revokeAccessToken(c); err != nil {
+			slog.ErrorContext(c.Request.Context(), "revoking access token", slog.Any("error", err))
+		}
+	}
+
+	data := gin.H{"user": user}
+	for key, value := range tokens {
+		data[key] = value
+	}
+	res.Data = data
+	c.JSON(http.StatusOK, res)
+}
+
+// ChangePassword sets a new password for the current user and ends every
+// other session. The session making the request stays signed in.
+func (repo *AuthRepo) ChangePassword(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqChangePassword{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+	claims := c.MustGet("claims").(*TokenClaims)
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword))
+	if err != nil {
+		errorMsg := "Invalid current password"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if !validatePassword(req.NewPassword, user.Email, c) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		res.Success = false
+		errorMsg := "Failed to hash password"
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"password":                string(hashedPassword),
+			"password_reset_required": false,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return revokeOtherSessions(tx, user, claims.SessionID)
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Password changed successfully"
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/metrics"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaPendingTTL     = 5 * time.Minute
+	recoveryCodeCount = 10
+
+	// totpPeriod and totpSkew match totp.Validate: 30 second steps, and the
+	// steps either side of the current one are accepted for clock drift.
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+type ReqMFACode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type ReqMFAVerify struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type ReqMFADisable struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// newRecoveryCode returns a code like "3f9a1-c07be".
+func newRecoveryCode() string {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	code := hex.EncodeToString(b)
+	return code[:5] + "-" + code[5:]
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// replaceRecoveryCodes drops the user's recovery codes and stores a new set.
+// The plain codes are returned so they can be shown once.
+func replaceRecoveryCodes(db *gorm.DB, user *models.User) ([]string, error) {
+	err := db.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		hash, err := bcrypt.GenerateFromPassword([]byte(codes[i]), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = models.RecoveryCode{UserID: user.ID, CodeHash: string(hash)}
+	}
+	if err := db.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// totpStep returns the time step code belongs to, among the steps around now
+// that are accepted.
+func totpStep(code string, secret string, now time.Time) (int64, bool) {
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		valid, err := hotp.ValidateCustom(code, uint64(step), secret, hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && valid {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// useTOTPStep records step as the last one a code was accepted for. It
+// reports false when a code of that step or a later one was accepted
+// already, so every code works once, even under concurrent requests.
+func useTOTPStep(db *gorm.DB, user *models.User, step int64) (bool, error) {
+	result := db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// verifyMFACode accepts a current TOTP code that wasn't used before or an
+// unused recovery code, which is used up.
+func verifyMFACode(db *gorm.DB, user *models.User, code string) (bool, error) {
+	if user.TOTPSecret == "" {
+		return false, nil
+	}
+	if step, ok := totpStep(strings.TrimSpace(code), user.TOTPSecret, time.Now()); ok {
+		return useTOTPStep(db, user, step)
+	}
+
+	var recoveryCodes []models.RecoveryCode
+	result := db.Where("user_id = ? AND used_at IS NULL", user.ID).Find(&recoveryCodes)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	code = normalizeRecoveryCode(code)
+	for _, recoveryCode := range recoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(recoveryCode.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		result = db.Model(&models.RecoveryCode{}).
+			Where("id = ? AND used_at IS NULL", recoveryCode.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return false, result.Error
+		}
+		return result.RowsAffected == 1, nil
+	}
+
+	return false, nil
+}
+
+// EnrollMFA generates a new TOTP secret for the current user. It only takes
+// effect once ConfirmMFA has seen a code from the authenticator app.
+func (repo *AuthRepo) EnrollMFA(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	user := c.MustGet("user").(*models.User)
+
+	if user.TOTPEnabledAt != nil {
+		errorMsg := "Two-factor authentication is already enabled"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusConflict, res)
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      Config.JWT.Issuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	result := requestDB(c, repo.DB).Model(user).Update("totp_secret", key.Secret())
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = gin.H{
+		"secret":      key.Secret(),
+		"otpauth_url": key.URL(),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ConfirmMFA enables two-factor authentication and returns the recovery
+// codes. They are stored hashed and can't be shown again.
+func (repo *AuthRepo) ConfirmMFA(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqMFACode{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	if user.TOTPEnabledAt != nil {
+		errorMsg := "Two-factor authentication is already enabled"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusConflict, res)
+		return
+	}
+	if user.TOTPSecret == "" {
+		errorMsg := "Two-factor authentication enrollment has not been started"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	step, ok := totpStep(strings.TrimSpace(req.Code), user.TOTPSecret, time.Now())
+	if !ok {
+		errorMsg := "Invalid authentication code"
+		errorCode := "mfa_invalid_code"
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &errorCode
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var codes []string
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		// The code that confirmed enrollment can't be used to log in.
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, user)
+		return err
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = gin.H{
+		"recovery_codes": codes,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// DisableMFA turns two-factor authentication off. It asks for the password
+// and a code so a stolen access token alone can't do it.
+func (repo *AuthRepo) DisableMFA(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqMFADisable{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	if user.TOTPEnabledAt == nil {
+		errorMsg := "Two-factor authentication is not enabled"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if err != nil {
+		errorMsg := "Invalid credentials"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	valid, err := verifyMFACode(requestDB(c, repo.DB), user, req.Code)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if !valid {
+		errorMsg := "Invalid authentication code"
+		errorCode := "mfa_invalid_code"
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &errorCode
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Two-factor authentication disabled"
+	c.JSON(http.StatusOK, res)
+}
+
+// VerifyMFA finishes a login started by Login for a user with two-factor
+// authentication. Wrong codes count against the login throttle like wrong
+// passwords do.
+func (repo *AuthRepo) VerifyMFA(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqMFAVerify{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	record, err := findUserToken(requestDB(c, repo.DB), req.MFAToken, TokenTypeMFAPending)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
+		c.JSON(tokenErrorStatus(err), res)
+		return
+	}
+	user := record.User
+	if user.DisabledAt != nil {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginDisabled).Inc()
+		errorMsg := ErrUserDisabled.Message
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &ErrUserDisabled.Code
+		c.JSON(http.StatusForbidden, res)
+		return
+	}
+
+	throttle := loginThrottle()
+	err = checkLoginThrottle(requestDB(c, repo.DB), throttle, user.Email, c.ClientIP())
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		if throttled, ok := err.(*loginThrottledError); ok {
+			metrics.LoginFailures.WithLabelValues(metrics.LoginThrottled).Inc()
+			errorCode := throttled.Code()
+			res.Code = &errorCode
+			c.Header("Retry-After", throttled.RetryAfterSeconds())
+			c.JSON(http.StatusTooManyRequests, res)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	valid, err := verifyMFACode(requestDB(c, repo.DB), &user, req.Code)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if !valid {
+		repo.recordLoginFailure(throttle, user.Email, c)
+		metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidMFACode).Inc()
+		errorMsg := "Invalid authentication code"
+		errorCode := "mfa_invalid_code"
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &errorCode
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	err = markUserTokenUsed(requestDB(c, repo.DB), record)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
+		c.JSON(tokenErrorStatus(err), res)
+		return
+	}
+
+	err = resetLoginFailures(requestDB(c, repo.DB), user.Email)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	data, err := issueLoginTokens(requestDB(c, repo.DB), user, c)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	metrics.LoginSuccesses.Inc()
+
+	res.Data = data
+	c.JSON(http.StatusOK, res)
+}
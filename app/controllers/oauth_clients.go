@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errOAuthClientNotFound = &service.Error{Message: "OAuth client not found", NotFound: true}
+
+type ReqOAuthClient struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	Confidential bool     `json:"confidential"`
+}
+
+func (repo *OAuthRepo) GetAllOAuthClients(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	user := c.MustGet("user").(*models.User)
+
+	var clients []models.OAuthClient
+	result := requestDB(c, repo.DB).Preload("Scopes").Where("user_id = ?", user.ID).Order("id").Find(&clients)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = clients
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateOAuthClient registers a client that may ask for scopes, which must be
+// permissions the user holds. A confidential client's secret is only part of
+// this response.
+func (repo *OAuthRepo) CreateOAuthClient(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqOAuthClient{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	for _, scope := range req.Scopes {
+		if !user.HasPermission(scope) {
+			errorMsg := "Scope not granted to user: " + scope
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			errorMsg := "Invalid redirect URI: " + redirectURI
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+	}
+	if !req.Confidential && len(req.RedirectURIs) == 0 {
+		errorMsg := "Public clients need at least one redirect URI"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var scopes []models.Permission
+	result := requestDB(c, repo.DB).Where("name IN ?", req.Scopes).Find(&scopes)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	client := models.OAuthClient{
+		UserID:       user.ID,
+		ClientID:     newRandomID(),
+		Confidential: req.Confidential,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       scopes,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	var secret string
+	if req.Confidential {
+		secret = newRandomID() + newRandomID()
+		client.SecretHash = hashToken(secret)
+	}
+	result = requestDB(c, repo.DB).Create(&client)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	data := gin.H{"client": client}
+	if secret != "" {
+		data["client_secret"] = secret
+	}
+	res.Data = data
+	c.JSON(http.StatusCreated, res)
+}
+
+// DeleteOAuthClient removes a client together with its pending authorization
+// codes. ValidateAccessToken rejects the tokens it was already given.
+func (repo *OAuthRepo) DeleteOAuthClient(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user := c.MustGet("user").(*models.User)
+	clientID, ok := idParam(c, errOAuthClientNotFound)
+	if !ok {
+		return
+	}
+
+	var client models.OAuthClient
+	result := requestDB(c, repo.DB).Where("user_id = ?", user.ID).First(&client, clientID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "OAuth client not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	err := requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("oauth_client_id = ?", client.ID).Delete(&models.OAuthAuthorizationCode{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&client).Association("Scopes").Clear()
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "OAuth client deleted successfully"
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthAccessTokenTTL = time.Hour
+	oauthCodeTTL        = 5 * time.Minute
+)
+
+type OAuthRepo struct {
+	DB *gorm.DB
+}
+
+// ReqOAuthAuthorize carries the RFC 6749 authorization request. GET reads it
+// from the query string to show the consent screen, POST from JSON together
+// with the user's decision.
+type ReqOAuthAuthorize struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Approve             bool   `form:"approve" json:"approve"`
+}
+
+// oauthError is an error from RFC 6749 section 5.2 / 4.1.2.1.
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func (e *oauthError) Error() string {
+	return e.Description
+}
+
+func OAuthController(db *gorm.DB) *OAuthRepo {
+	return &OAuthRepo{DB: db}
+}
+
+// writeOAuthError answers the OAuth endpoints, which speak plain RFC 6749 JSON
+// instead of the JsonResponse envelope.
+func writeOAuthError(c *gin.Context, status int, err *oauthError) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{
+		"error":             err.Code,
+		"error_description": err.Description,
+	})
+}
+
+// authenticateOAuthClient identifies the client of a token, introspection or
+// revocation request by HTTP Basic auth or client_id/client_secret form
+// fields. Public clients only send their client_id.
+func authenticateOAuthClient(db *gorm.DB, c *gin.Context) (*models.OAuthClient, *oauthError, error) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+	invalidClient := &oauthError{Code: "invalid_client", Description: "Client authentication failed"}
+	if clientID == "" {
+		return nil, invalidClient, nil
+	}
+
+	var client models.OAuthClient
+	result := db.Preload("Scopes").Preload("User.Roles").Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, invalidClient, nil
+		}
+		return nil, nil, result.Error
+	}
+
+	if client.Confidential {
+		if subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, invalidClient, nil
+		}
+	} else if clientSecret != "" {
+		return nil, invalidClient, nil
+	}
+
+	return &client, nil, nil
+}
+
+// requestedScopes splits scope and checks every entry against the client's
+// registration. An empty scope asks for everything the client may have.
+func requestedScopes(client *models.OAuthClient, scope string) ([]string, *oauthError) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		for _, permission := range client.Scopes {
+			scopes = append(scopes, permission.Name)
+		}
+		return scopes, nil
+	}
+	for _, s := range scopes {
+		if !client.HasScope(s) {
+			return nil, &oauthError{Code: "invalid_scope", Description: "Scope not allowed for client: " + s}
+		}
+	}
+	return scopes, nil
+}
+
+// createOAuthAccessToken signs an access token for user that RequirePermission
+// limits to scopes.
+func createOAuthAccessToken(user models.User, client *models.OAuthClient, scopes []string) (string, error) {
+	claims := newTokenClaims(TokenTypeAccess, time.Now().Add(oauthAccessTokenTTL), user)
+	claims.ClientID = client.ClientID
+	claims.Scope = strings.Join(scopes, " ")
+	return signToken(claims)
+}
+
+// pkceChallenge is the S256 code challenge of verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorizeRedirect appends params to the client's redirect URI.
+func authorizeRedirect(redirectURI string, params url.Values) string {
+	u, _ := url.Parse(redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// findAuthorizeClient resolves the client and redirect URI of an authorization
+// request. Until both are known to be valid, errors must not be redirected.
+func (repo *OAuthRepo) findAuthorizeClient(req ReqOAuthAuthorize, c *gin.Context) (*models.OAuthClient, *oauthError, error) {
+	var client models.OAuthClient
+	result := requestDB(c, repo.DB).Preload("Scopes").Where("client_id = ?", req.ClientID).First(&client)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, &oauthError{Code: "invalid_client", Description: "Unknown client"}, nil
+		}
+		return nil, nil, result.Error
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, &oauthError{Code: "invalid_request", Description: "Redirect URI not registered for client"}, nil
+	}
+	return &client, nil, nil
+}
+
+// checkAuthorizeRequest validates the rest of an authorization request and
+// returns the scopes to grant. PKCE with S256 is required for every client.
+func checkAuthorizeRequest(req ReqOAuthAuthorize, client *models.OAuthClient, user *models.User) ([]string, *oauthError) {
+	if req.ResponseType != "code" {
+		return nil, &oauthError{Code: "unsupported_response_type", Description: "Only the code response type is supported"}
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, &oauthError{Code: "invalid_request", Description: "PKCE with code_challenge_method S256 is required"}
+	}
+
+	scopes, oauthErr := requestedScopes(client, req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	for _, scope := range scopes {
+		if !user.HasPermission(scope) {
+			return nil, &oauthError{Code: "invalid_scope", Description: "Scope not granted to user: " + scope}
+		}
+	}
+	return scopes, nil
+}
+
+// Authorize describes an authorization request so the frontend can ask the
+// signed in user for consent.
+func (repo *OAuthRepo) Authorize(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqOAuthAuthorize{}
+	_ = c.ShouldBindQuery(&req)
+
+	user := c.MustGet("user").(*models.User)
+
+	var scopes []string
+	client, oauthErr, err := repo.findAuthorizeClient(req, c)
+	if err == nil && oauthErr == nil {
+		scopes, oauthErr = checkAuthorizeRequest(req, client, user)
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if oauthErr != nil {
+		errorMsg := oauthErr.Description
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &oauthErr.Code
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res.Data = gin.H{
+		"client_id":    client.ClientID,
+		"client_name":  client.Name,
+		"redirect_uri": req.RedirectURI,
+		"scopes":       scopes,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ApproveAuthorization records the user's decision. The response holds the
+// URL to send the browser back to, carrying either the code or the error.
+func (repo *OAuthRepo) ApproveAuthorization(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqOAuthAuthorize{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	client, oauthErr, err := repo.findAuthorizeClient(req, c)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if oauthErr != nil {
+		errorMsg := oauthErr.Description
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = &oauthErr.Code
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	scopes, oauthErr := checkAuthorizeRequest(req, client, user)
+	if oauthErr == nil && !req.Approve {
+		oauthErr = &oauthError{Code: "access_denied", Description: "The user denied the request"}
+	}
+	if oauthErr != nil {
+		params.Set("error", oauthErr.Code)
+		params.Set("error_description", oauthErr.Description)
+		res.Data = gin.H{"redirect_to": authorizeRedirect(req.RedirectURI, params)}
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	code := newRandomID() + newRandomID()
+	record := models.OAuthAuthorizationCode{
+		OAuthClientID: client.ID,
+		UserID:        user.ID,
+		CodeHash:      hashToken(code),
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	}
+	result := requestDB(c, repo.DB).Create(&record)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	params.Set("code", code)
+	res.Data = gin.H{"redirect_to": authorizeRedirect(req.RedirectURI, params)}
+	c.JSON(http.StatusOK, res)
+}
+
+// Token is the RFC 6749 token endpoint for the client_credentials and
+// authorization_code grants.
+func (repo *OAuthRepo) Token(c *gin.Context) {
+	client, oauthErr, err := authenticateOAuthClient(requestDB(c, repo.DB), c)
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+	if oauthErr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	var (
+		user   models.User
+		scopes []string
+	)
+	switch c.PostForm("grant_type") {
+	case "client_credentials":
+		if !client.Confidential {
+			oauthErr = &oauthError{Code: "unauthorized_client", Description: "Public clients can't use client_credentials"}
+			break
+		}
+		user = client.User
+		scopes, oauthErr = requestedScopes(client, c.PostForm("scope"))
+	case "authorization_code":
+		var code *models.OAuthAuthorizationCode
+		code, oauthErr, err = repo.redeemAuthorizationCode(client, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"), c)
+		if code != nil {
+			user = code.User
+			scopes = strings.Fields(code.Scope)
+		}
+	default:
+		oauthErr = &oauthError{Code: "unsupported_grant_type", Description: "Unsupported grant type"}
+	}
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+	if oauthErr != nil {
+		writeOAuthError(c, http.StatusBadRequest, oauthErr)
+		return
+	}
+
+	accessToken, err := createOAuthAccessToken(user, client, scopes)
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(oauthAccessTokenTTL.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// redeemAuthorizationCode checks a code against the client, redirect URI and
+// PKCE verifier it was issued for and uses it up.
+func (repo *OAuthRepo) redeemAuthorizationCode(client *models.OAuthClient, code string, redirectURI string, verifier string, c *gin.Context) (*models.OAuthAuthorizationCode, *oauthError, error) {
+	invalidGrant := &oauthError{Code: "invalid_grant", Description: "Invalid authorization code"}
+
+	var record models.OAuthAuthorizationCode
+	result := requestDB(c, repo.DB).Preload("User.Roles").Where("code_hash = ?", hashToken(code)).First(&record)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, invalidGrant, nil
+		}
+		return nil, nil, result.Error
+	}
+
+	if record.UsedAt != nil || record.OAuthClientID != client.ID || time.Now().After(record.ExpiresAt) {
+		return nil, invalidGrant, nil
+	}
+	if record.RedirectURI != redirectURI {
+		return nil, &oauthError{Code: "invalid_grant", Description: "Redirect URI does not match"}, nil
+	}
+	if verifier == "" || subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(record.CodeChallenge)) != 1 {
+		return nil, &oauthError{Code: "invalid_grant", Description: "Invalid code verifier"}, nil
+	}
+
+	result = requestDB(c, repo.DB).Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, invalidGrant, nil
+	}
+
+	return &record, nil, nil
+}
+
+// Introspect implements RFC 7662 for confidential clients. Anything that
+// isn't a valid access token issued to the calling client is reported as
+// inactive, so clients can't learn about each other's or users' tokens.
+func (repo *OAuthRepo) Introspect(c *gin.Context) {
+	client, oauthErr, err := authenticateOAuthClient(requestDB(c, repo.DB), c)
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+	if oauthErr == nil && !client.Confidential {
+		oauthErr = &oauthError{Code: "invalid_client", Description: "Only confidential clients can introspect tokens"}
+	}
+	if oauthErr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	user, claims, err := ValidateAccessToken(c.PostForm("token"), requestDB(c, repo.DB))
+	if err != nil {
+		if _, ok := err.(*TokenError); ok {
+			c.JSON(http.StatusOK, gin.H{"active": false})
+			return
+		}
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+	if claims.ClientID != client.ClientID {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"username":   user.Email,
+		"sub":        claims.Subject,
+		"iss":        claims.Issuer,
+		"aud":        claims.Audience,
+		"jti":        claims.Id,
+		"iat":        claims.IssuedAt,
+		"nbf":        claims.NotBefore,
+		"exp":        claims.ExpiresAt,
+		"client_id":  claims.ClientID,
+		"scope":      claims.Scope,
+	})
+}
+
+// Revoke implements RFC 7009. A client may only revoke access tokens issued
+// to it; the answer is 200 either way so tokens can't be probed.
+func (repo *OAuthRepo) Revoke(c *gin.Context) {
+	client, oauthErr, err := authenticateOAuthClient(requestDB(c, repo.DB), c)
+	if err != nil {
+		writeOAuthError(c, http.StatusInternalServerError, &oauthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+	if oauthErr != nil {
+		writeOAuthError(c, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	claims, err := parseToken(c.PostForm("token"), TokenTypeAccess)
+	if err == nil && claims.ClientID == client.ClientID {
+		err = TokenDenylist.Add(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			writeOAuthError(c, http.StatusServiceUnavailable, &oauthError{Code: "temporarily_unavailable", Description: err.Error()})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
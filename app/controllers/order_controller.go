@@ -3,12 +3,10 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"strconv"
-	"strings"
-	"time"
 
-	"github.com/fajaaro/dbo/app"
 	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+	"github.com/fajaaro/dbo/app/store"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/gin-gonic/gin"
@@ -16,7 +14,8 @@ import (
 )
 
 type OrderRepo struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Orders *service.OrderService
 }
 
 type ReqOrder struct {
@@ -27,9 +26,25 @@ type ReqOrder struct {
 	PaymentStatus string  `binding:"required" json:"payment_status"`
 }
 
+func (req ReqOrder) input() service.OrderInput {
+	return service.OrderInput{
+		CustomerID:    req.CustomerID,
+		ProductName:   req.ProductName,
+		Quantity:      req.Quantity,
+		TotalPrice:    req.TotalPrice,
+		PaymentStatus: req.PaymentStatus,
+	}
+}
+
+// handleValidationError answers a bind error with a 400. A body that isn't
+// JSON, or holds a value of the wrong type, isn't a validation error and is
+// only reported as invalid.
 func handleValidationError(err error, c *gin.Context) {
-	validationErrors := err.(validator.ValidationErrors)
-	errorMsg := validationErrors[0].Field() + " not valid"
+	errorMsg := "invalid request body"
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		errorMsg = validationErrors[0].Field() + " not valid"
+	}
 	res := models.JsonResponse{
 		Success: false,
 		Error:   &errorMsg,
@@ -38,65 +53,18 @@ func handleValidationError(err error, c *gin.Context) {
 	c.Abort()
 }
 
-func validateOrderInputAndCustomerExistence(req *ReqOrder, db *gorm.DB, res *models.JsonResponse, c *gin.Context) error {
-	var customer models.Customer
-	result := db.First(&customer, req.CustomerID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Customer not found"
-			return errors.New(errorMsg)
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
-		return errors.New(errorMsg)
-	}
-
-	req.PaymentStatus = strings.ToLower(req.PaymentStatus)
-	if req.PaymentStatus != "paid" && req.PaymentStatus != "unpaid" {
-		errorMsg := "Invalid payment status"
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		return errors.New(errorMsg)
-	}
-
-	return nil
-}
-
-func OrderController() *OrderRepo {
-	return &OrderRepo{DB: app.InitDb()}
+func OrderController(db *gorm.DB) *OrderRepo {
+	orders := service.NewOrderService(store.NewGormOrderStore(db), store.NewGormCustomerStore(db))
+	return &OrderRepo{DB: db, Orders: orders}
 }
 
 func (repo *OrderRepo) GetAllOrders(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	page := c.DefaultQuery("page", "1")
-	pageNum, _ := strconv.Atoi(page)
-	limit := c.DefaultQuery("limit", "10")
-	limitNum, _ := strconv.Atoi(limit)
-	search := c.DefaultQuery("search", "")
-	search = strings.ToLower(search)
-
-	var orders []models.Order
-	query := repo.DB.Model(&models.Order{})
-
-	if search != "" {
-		query = query.Where("product_name ILIKE ?", "%"+search+"%")
-	}
-
-	var count int64
-	query.Count(&count)
-
-	query = query.Offset((pageNum - 1) * limitNum).Limit(limitNum).Find(&orders)
-
-	if query.Error != nil {
-		errorMsg := query.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	orders, count, err := repo.Orders.List(c.Request.Context(), tenantID(c), pageQuery(c))
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -111,22 +79,14 @@ func (repo *OrderRepo) GetOrderDetail(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	orderID := c.Param("id")
+	orderID, ok := idParam(c, service.ErrOrderNotFound)
+	if !ok {
+		return
+	}
 
-	var order models.Order
-	result := repo.DB.First(&order, orderID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Order not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	order, err := repo.Orders.Get(c.Request.Context(), tenantID(c), orderID)
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -144,32 +104,9 @@ func (repo *OrderRepo) InsertOrder(c *gin.Context) {
 		return
 	}
 
-	if err := validateOrderInputAndCustomerExistence(&req, repo.DB, &res, c); err != nil {
-		errorMsg := err.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	order := &models.Order{
-		CustomerID:    req.CustomerID,
-		ProductName:   req.ProductName,
-		Quantity:      req.Quantity,
-		TotalPrice:    req.TotalPrice,
-		PaymentStatus: req.PaymentStatus,
-	}
-	if req.PaymentStatus == "paid" {
-		paidAt := time.Now()
-		order.PaidAt = &paidAt
-	}
-	result := repo.DB.Create(&order)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	order, err := repo.Orders.Create(c.Request.Context(), tenantID(c), req.input())
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -181,22 +118,8 @@ func (repo *OrderRepo) UpdateOrder(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	orderID := c.Param("id")
-
-	var order models.Order
-	result := repo.DB.First(&order, orderID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Order not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	orderID, ok := idParam(c, service.ErrOrderNotFound)
+	if !ok {
 		return
 	}
 
@@ -207,32 +130,9 @@ func (repo *OrderRepo) UpdateOrder(c *gin.Context) {
 		return
 	}
 
-	if err := validateOrderInputAndCustomerExistence(&req, repo.DB, &res, c); err != nil {
-		errorMsg := err.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusBadRequest, res)
-		c.Abort()
-		return
-	}
-
-	order.ProductName = req.ProductName
-	order.Quantity = req.Quantity
-	order.TotalPrice = req.TotalPrice
-	order.PaymentStatus = req.PaymentStatus
-	if req.PaymentStatus == "paid" {
-		paidAt := time.Now()
-		order.PaidAt = &paidAt
-	} else {
-		order.PaidAt = nil
-	}
-
-	result = repo.DB.Save(&order)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	order, err := repo.Orders.Update(c.Request.Context(), tenantID(c), orderID, req.input())
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
@@ -244,31 +144,14 @@ func (repo *OrderRepo) DeleteOrder(c *gin.Context) {
 	c.Header("Content-Type", "application/json")
 	res := models.JsonResponse{Success: true}
 
-	orderID := c.Param("id")
-
-	var order models.Order
-	result := repo.DB.First(&order, orderID)
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			errorMsg := "Order not found"
-			res.Success = false
-			res.Error = &errorMsg
-			c.JSON(http.StatusNotFound, res)
-			return
-		}
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	orderID, ok := idParam(c, service.ErrOrderNotFound)
+	if !ok {
 		return
 	}
 
-	result = repo.DB.Delete(&order)
-	if result.Error != nil {
-		errorMsg := result.Error.Error()
-		res.Success = false
-		res.Error = &errorMsg
-		c.JSON(http.StatusInternalServerError, res)
+	err := repo.Orders.Delete(c.Request.Context(), tenantID(c), orderID)
+	if err != nil {
+		respondServiceError(err, c)
 		return
 	}
 
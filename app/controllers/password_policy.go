@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BreachedPasswords is the list new passwords are checked against. It is
+// swapped for PASSWORD_BREACHED_LIST at startup when that is set.
+var BreachedPasswords password.RangeSource = password.Bundled()
+
+func passwordPolicy() password.Policy {
+	policy := password.Policy{
+		MinLength:     Config.Password.MinLength,
+		MaxLength:     Config.Password.MaxLength,
+		RequireUpper:  Config.Password.RequireUpper,
+		RequireLower:  Config.Password.RequireLower,
+		RequireDigit:  Config.Password.RequireDigit,
+		RequireSymbol: Config.Password.RequireSymbol,
+	}
+	if Config.Password.CheckBreached {
+		policy.Breached = BreachedPasswords
+	}
+	return policy
+}
+
+// validatePassword checks a new password for the account with email against
+// the policy, answering 400 with the failed rule or 500 itself when it fails.
+func validatePassword(newPassword string, email string, c *gin.Context) bool {
+	res := models.JsonResponse{Success: true}
+
+	err := passwordPolicy().Validate(newPassword, email)
+	if err == nil {
+		return true
+	}
+
+	errorMsg := err.Error()
+	res.Success = false
+	res.Error = &errorMsg
+	if policyErr, ok := err.(*password.PolicyError); ok {
+		res.Code = &policyErr.Code
+		c.JSON(http.StatusBadRequest, res)
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, res)
+	return false
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errRefreshTokenInvalid = &TokenError{Code: "refresh_token_invalid", Message: "Invalid refresh token"}
+	errRefreshTokenExpired = &TokenError{Code: "token_expired", Message: "Expired refresh token"}
+	errRefreshTokenRevoked = &TokenError{Code: "token_revoked", Message: "Refresh token has been revoked"}
+	errRefreshTokenReused  = &TokenError{Code: "refresh_token_reused", Message: "Refresh token reuse detected"}
+)
+
+func newRandomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// hashToken returns the value stored in the database for a token, so a leaked
+// table can't be replayed against the API.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken signs a new refresh token for user and persists its hash
+// in the given family. Pass an empty familyID to start a new family (login).
+func issueRefreshToken(db *gorm.DB, user models.User, familyID string, c *gin.Context) (string, *models.RefreshToken, error) {
+	if familyID == "" {
+		familyID = newRandomID()
+	}
+
+	exp := time.Now().Add(Config.JWT.RefreshTTL)
+	token, err := createToken(TokenTypeRefresh, exp, user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := &models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		FamilyID:  familyID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: exp,
+	}
+	if err := db.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+
+	return token, record, nil
+}
+
+// rotateRefreshToken invalidates the presented refresh token and issues its
+// successor in the same family. Presenting a token that was already rotated
+// revokes the whole family. The presented token is returned with its user.
+func rotateRefreshToken(db *gorm.DB, refreshToken string, c *gin.Context) (*models.RefreshToken, string, error) {
+	var stored models.RefreshToken
+	result := db.Preload("User.Roles").Where("token_hash = ?", hashToken(refreshToken)).First(&stored)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, "", errRefreshTokenInvalid
+		}
+		return nil, "", result.Error
+	}
+
+	if stored.RevokedAt != nil && stored.ReplacedByID == nil {
+		return nil, "", errRefreshTokenRevoked
+	}
+
+	if stored.RevokedAt != nil {
+		if err := revokeRefreshTokenFamily(db, stored.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", errRefreshTokenReused
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, "", errRefreshTokenExpired
+	}
+
+	var revokedSessions int64
+	result = db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NOT NULL", stored.FamilyID).
+		Count(&revokedSessions)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if revokedSessions > 0 {
+		return nil, "", errRefreshTokenRevoked
+	}
+
+	var newToken string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Only one concurrent refresh may win; the loser is treated as reuse.
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", stored.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		token, record, err := issueRefreshToken(tx, stored.User, stored.FamilyID, c)
+		if err != nil {
+			return err
+		}
+		newToken = token
+
+		err = tx.Model(&stored).Update("replaced_by_id", record.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("family_id = ?", stored.FamilyID).
+			Update("last_refreshed_at", time.Now()).Error
+	})
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			if err := revokeRefreshTokenFamily(db, stored.FamilyID); err != nil {
+				return nil, "", err
+			}
+		}
+		return nil, "", err
+	}
+
+	return &stored, newToken, nil
+}
+
+// revokeRefreshTokenFamily ends the session of familyID: its refresh tokens
+// stop working and ValidateAccessToken rejects its access tokens.
+func revokeRefreshTokenFamily(db *gorm.DB, familyID string) error {
+	now := time.Now()
+	err := db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,12 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// requestDB binds db to the request, so its queries are logged with the
+// request ID set by middlewares.RequestID().
+func requestDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleRepo struct {
+	DB *gorm.DB
+}
+
+type ReqRole struct {
+	Role string `json:"role" binding:"required"`
+}
+
+var errUnknownRole = errors.New("Role not found")
+
+func RoleController(db *gorm.DB) *RoleRepo {
+	return &RoleRepo{DB: db}
+}
+
+// defaultRole is granted to users added to a tenant without an explicit role.
+func defaultRole() string {
+	return Config.Auth.DefaultRole
+}
+
+func assignRole(db *gorm.DB, user *models.User, roleName string) error {
+	var role models.Role
+	result := db.Where("name = ?", roleName).First(&role)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return errUnknownRole
+		}
+		return result.Error
+	}
+
+	return db.Model(user).Association("Roles").Append(&role)
+}
+
+// BootstrapAdmin grants the admin role to an existing user. It backs the
+// bootstrap-admin command for instances whose first user predates roles.
+func BootstrapAdmin(db *gorm.DB, email string) error {
+	var user models.User
+	result := db.Where("email = ?", email).First(&user)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return assignRole(db, &user, models.RoleAdmin)
+}
+
+// NewUser describes an account created by the user create command.
+type NewUser struct {
+	Email        string
+	Password     string
+	Name         string
+	TenantID     uint
+	Organization string
+	Admin        bool
+}
+
+// CreateUser adds an account outside of the API. Without a TenantID it starts
+// a new tenant named after Organization, or the email. The user gets the
+// admin role when Admin is set and DEFAULT_ROLE otherwise, and since an
+// operator vouched for the address it counts as verified.
+func CreateUser(db *gorm.DB, newUser NewUser) (*models.User, error) {
+	err := passwordPolicy().Validate(newUser.Password, newUser.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	err = db.Model(&models.User{}).Where("email = ?", newUser.Email).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("Email already exists")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	roleName := defaultRole()
+	if newUser.Admin {
+		roleName = models.RoleAdmin
+	}
+
+	verifiedAt := time.Now()
+	user := &models.User{
+		TenantID:        newUser.TenantID,
+		Email:           newUser.Email,
+		Name:            newUser.Name,
+		Password:        string(hashedPassword),
+		EmailVerifiedAt: &verifiedAt,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if user.TenantID == 0 {
+			tenantName := newUser.Organization
+			if tenantName == "" {
+				tenantName = newUser.Email
+			}
+			tenant := models.Tenant{Name: tenantName}
+			if err := tx.Create(&tenant).Error; err != nil {
+				return err
+			}
+			user.TenantID = tenant.ID
+		} else if err := tx.First(&models.Tenant{}, user.TenantID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.New("Tenant not found")
+			}
+			return err
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return assignRole(tx, user, roleName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (repo *RoleRepo) GetAllRoles(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	var roles []models.Role
+	result := requestDB(c, repo.DB).Preload("Permissions").Order("id").Find(&roles)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = roles
+	c.JSON(http.StatusOK, res)
+}
+
+func (repo *RoleRepo) GrantRole(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqRole{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	userID, ok := idParam(c, errUserNotFound)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	result := requestDB(c, repo.DB).Scopes(tenantScope(c)).Preload("Roles").First(&user, userID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "User not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	err = assignRole(requestDB(c, repo.DB), &user, req.Role)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		if err == errUnknownRole {
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = map[string]interface{}{
+		"user_id": user.ID,
+		"roles":   user.RoleNames(),
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (repo *RoleRepo) RevokeRole(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	userID, ok := idParam(c, errUserNotFound)
+	if !ok {
+		return
+	}
+	roleName := c.Param("role")
+
+	var user models.User
+	result := requestDB(c, repo.DB).Scopes(tenantScope(c)).Preload("Roles").First(&user, userID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "User not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	var role models.Role
+	result = requestDB(c, repo.DB).Where("name = ?", roleName).First(&role)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "Role not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	// Never lock everybody out of role management.
+	if role.Name == models.RoleAdmin {
+		var adminCount int64
+		result = requestDB(c, repo.DB).Table("user_roles").
+			Joins("JOIN users ON users.id = user_roles.user_id").
+			Where("user_roles.role_id = ? AND users.tenant_id = ?", role.ID, user.TenantID).
+			Count(&adminCount)
+		if result.Error != nil {
+			errorMsg := result.Error.Error()
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusInternalServerError, res)
+			return
+		}
+		hasRole := false
+		for _, r := range user.Roles {
+			hasRole = hasRole || r.ID == role.ID
+		}
+		if hasRole && adminCount <= 1 {
+			errorMsg := "Cannot revoke the last admin"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+	}
+
+	err := requestDB(c, repo.DB).Model(&user).Association("Roles").Delete(&role)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = map[string]interface{}{
+		"user_id": user.ID,
+		"roles":   user.RoleNames(),
+	}
+	c.JSON(http.StatusOK, res)
+}
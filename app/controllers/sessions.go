@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// activeSessions returns the sessions of userID that are neither revoked nor
+// past the lifetime of their last refresh token, newest first.
+func activeSessions(db *gorm.DB, userID uint) ([]models.Session, error) {
+	staleBefore := time.Now().Add(-Config.JWT.RefreshTTL)
+
+	var sessions []models.Session
+	result := db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("last_refreshed_at > ? OR (last_refreshed_at IS NULL AND created_at > ?)", staleBefore, staleBefore).
+		Order("id DESC").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+var errSessionNotFound = &service.Error{Message: "Session not found", NotFound: true}
+
+// findSession looks up the session of userID named by the route parameter
+// param, answering 404 or 500 itself when it can't.
+func findSession(db *gorm.DB, userID uint, param string, c *gin.Context) (*models.Session, bool) {
+	res := models.JsonResponse{Success: true}
+
+	sessionID, ok := uintParam(c, param, errSessionNotFound)
+	if !ok {
+		return nil, false
+	}
+
+	var session models.Session
+	result := db.Where("user_id = ?", userID).First(&session, sessionID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "Session not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return nil, false
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// GetSessions lists where the current user is logged in. The session of the
+// request is flagged as current.
+func (repo *AuthRepo) GetSessions(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user := c.MustGet("user").(*models.User)
+	claims := c.MustGet("claims").(*TokenClaims)
+
+	sessions, err := activeSessions(requestDB(c, repo.DB), user.ID)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == claims.SessionID
+	}
+
+	res.Data = sessions
+	c.JSON(http.StatusOK, res)
+}
+
+// RevokeSession logs the current user out of one session, e.g. a lost device.
+func (repo *AuthRepo) RevokeSession(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user := c.MustGet("user").(*models.User)
+
+	session, ok := findSession(requestDB(c, repo.DB), user.ID, "id", c)
+	if !ok {
+		return
+	}
+
+	err := revokeRefreshTokenFamily(requestDB(c, repo.DB), session.FamilyID)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Session revoked successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// GetUserSessions lets support see where a user of the tenant is logged in.
+func (repo *UserRepo) GetUserSessions(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	sessions, err := activeSessions(requestDB(c, repo.DB), user.ID)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = sessions
+	c.JSON(http.StatusOK, res)
+}
+
+// RevokeUserSession lets support log a user of the tenant out of one session.
+func (repo *UserRepo) RevokeUserSession(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	session, ok := findSession(requestDB(c, repo.DB), user.ID, "session_id", c)
+	if !ok {
+		return
+	}
+
+	err := revokeRefreshTokenFamily(requestDB(c, repo.DB), session.FamilyID)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Session revoked successfully"
+	c.JSON(http.StatusOK, res)
+}
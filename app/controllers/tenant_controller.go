@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/fajaaro/dbo/app/models"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TenantRepo struct {
+	DB *gorm.DB
+}
+
+type ReqTenantUser struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"`
+}
+
+func TenantController(db *gorm.DB) *TenantRepo {
+	return &TenantRepo{DB: db}
+}
+
+// tenantID returns the tenant of the caller, set by middlewares.JWT().
+func tenantID(c *gin.Context) uint {
+	return c.MustGet("tenant_id").(uint)
+}
+
+// tenantScope restricts a query to rows of the caller's tenant. Every query
+// on tenant-owned tables (users, customers, orders) must go through it.
+func tenantScope(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	id := tenantID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", id)
+	}
+}
+
+func (repo *TenantRepo) GetTenant(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	var tenant models.Tenant
+	result := requestDB(c, repo.DB).First(&tenant, tenantID(c))
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = tenant
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateTenantUser adds an account to the caller's tenant. Registering always
+// creates a new tenant, so this is how colleagues join an existing one.
+func (repo *TenantRepo) CreateTenantUser(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqTenantUser{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	if !validatePassword(req.Password, req.Email, c) {
+		return
+	}
+
+	var count int64
+	result := requestDB(c, repo.DB).Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+	if count > 0 {
+		errorMsg := "Email already exists"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		c.Abort()
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		res.Success = false
+		errorMsg := "Failed to hash password"
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		c.Abort()
+		return
+	}
+
+	roleName := req.Role
+	if roleName == "" {
+		roleName = defaultRole()
+	}
+
+	user := &models.User{
+		TenantID: tenantID(c),
+		Email:    req.Email,
+		Password: string(hashedPassword),
+	}
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return assignRole(tx, user, roleName)
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		if err == errUnknownRole {
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = map[string]interface{}{
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"email":     user.Email,
+		"roles":     user.RoleNames(),
+	}
+	c.JSON(http.StatusCreated, res)
+}
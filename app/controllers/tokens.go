@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/keys"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+const (
+	TokenTypeAccess            = "access_token"
+	TokenTypeRefresh           = "refresh_token"
+	TokenTypeEmailVerification = models.UserTokenEmailVerification
+	TokenTypePasswordReset     = models.UserTokenPasswordReset
+	TokenTypeMFAPending        = models.UserTokenMFAPending
+)
+
+const impersonationTokenTTL = 10 * time.Minute
+
+// SigningKeys signs and verifies every token. It is set at startup, after the
+// configuration is loaded, to HS256 with SECRET_KEY or to the PEM keys in
+// JWT_KEYS_DIR.
+var SigningKeys *keys.Manager
+
+// TokenClaims are the claims of every token. The subject is the user's ID,
+// which unlike the email never moves to another account. SessionID is set on
+// access tokens from a login, ClientID and Scope on those issued to OAuth
+// clients, Actor on those an admin uses to impersonate the subject and Email
+// on those mailed to the user, to the address they were mailed to.
+type TokenClaims struct {
+	TokenType string      `json:"token_type"`
+	Roles     []string    `json:"roles,omitempty"`
+	SessionID string      `json:"sid,omitempty"`
+	ClientID  string      `json:"client_id,omitempty"`
+	Scope     string      `json:"scope,omitempty"`
+	Actor     *TokenActor `json:"act,omitempty"`
+	Email     string      `json:"email,omitempty"`
+	jwt.StandardClaims
+}
+
+// TokenActor is the RFC 8693 "act" claim: who is acting as the subject. Email
+// labels the actor in logs and the audit trail.
+type TokenActor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+// tokenSubject returns the subject of the tokens of the user with id.
+func tokenSubject(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// subjectUserID returns the user ID a subject names. Tokens from before the
+// subject was the ID name an email and don't parse.
+func subjectUserID(subject string) (uint, bool) {
+	id, err := strconv.ParseUint(subject, 10, 0)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// HasScope reports whether permission is one of the space separated scopes.
+func (c *TokenClaims) HasScope(permission string) bool {
+	for _, scope := range strings.Fields(c.Scope) {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenError is returned for every token that fails validation. Code is sent
+// to clients next to the message so they can tell the failures apart.
+type TokenError struct {
+	Code    string
+	Message string
+}
+
+func (e *TokenError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrTokenMalformed   = &TokenError{Code: "token_malformed", Message: "Malformed token"}
+	ErrTokenSignature   = &TokenError{Code: "token_invalid_signature", Message: "Invalid token signature"}
+	ErrTokenExpired     = &TokenError{Code: "token_expired", Message: "Token has expired"}
+	ErrTokenNotValidYet = &TokenError{Code: "token_not_valid_yet", Message: "Token is not valid yet"}
+	ErrTokenWrongType   = &TokenError{Code: "token_wrong_type", Message: "Wrong token type"}
+	ErrTokenIssuer      = &TokenError{Code: "token_invalid_issuer", Message: "Invalid token issuer"}
+	ErrTokenAudience    = &TokenError{Code: "token_invalid_audience", Message: "Invalid token audience"}
+	ErrTokenRevoked     = &TokenError{Code: "token_revoked", Message: "Token has been revoked"}
+	ErrTokenUnknownUser = &TokenError{Code: "token_unknown_user", Message: "Unknown user in token"}
+	ErrUserDisabled     = &TokenError{Code: "user_disabled", Message: "User account is disabled"}
+)
+
+// createToken signs a token of tokenType for user. The roles claim is taken
+// from user.Roles, so preload them first.
+func createToken(tokenType string, exp time.Time, user models.User) (string, error) {
+	return signToken(newTokenClaims(tokenType, exp, user))
+}
+
+// createSessionAccessToken signs an access token for the login session whose
+// refresh token family is familyID.
+func createSessionAccessToken(user models.User, familyID string) (string, error) {
+	claims := newTokenClaims(TokenTypeAccess, time.Now().Add(Config.JWT.AccessTTL), user)
+	claims.SessionID = familyID
+	return signToken(claims)
+}
+
+// createImpersonationToken signs a short-lived access token for user that
+// names actor, the admin behind it, in the act claim.
+func createImpersonationToken(user models.User, actor models.User) (string, error) {
+	claims := newTokenClaims(TokenTypeAccess, time.Now().Add(impersonationTokenTTL), user)
+	claims.Actor = &TokenActor{Subject: tokenSubject(actor.ID), Email: actor.Email}
+	return signToken(claims)
+}
+
+// IssueAccessToken signs an access token for the user with email that is
+// valid for ttl. It backs the token issue command for scripts.
+func IssueAccessToken(db *gorm.DB, email string, ttl time.Duration) (string, error) {
+	var user models.User
+	result := db.Preload("Roles").Where("email = ?", email).First(&user)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if user.DisabledAt != nil {
+		return "", ErrUserDisabled
+	}
+
+	return createToken(TokenTypeAccess, time.Now().Add(ttl), user)
+}
+
+func newTokenClaims(tokenType string, exp time.Time, user models.User) TokenClaims {
+	now := time.Now()
+	return TokenClaims{
+		TokenType: tokenType,
+		Roles:     user.RoleNames(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        newRandomID(),
+			Subject:   tokenSubject(user.ID),
+			Issuer:    Config.JWT.Issuer,
+			Audience:  Config.JWT.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: exp.Unix(),
+		},
+	}
+}
+
+// signToken signs claims with the current signing key.
+func signToken(claims TokenClaims) (string, error) {
+	return SigningKeys.Sign(claims)
+}
+
+// parseToken verifies the signature and every registered claim of tokenStr
+// and checks that it is a token of tokenType.
+func parseToken(tokenStr string, tokenType string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, SigningKeys.Keyfunc)
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, ErrTokenMalformed
+		}
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return nil, ErrTokenMalformed
+		case validationErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+			return nil, ErrTokenSignature
+		case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+			return nil, ErrTokenExpired
+		case validationErr.Errors&(jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) != 0:
+			return nil, ErrTokenNotValidYet
+		default:
+			return nil, ErrTokenMalformed
+		}
+	}
+
+	// StandardClaims.Valid treats missing exp/iat/nbf as valid, so require them.
+	if claims.Id == "" || claims.Subject == "" || claims.ExpiresAt == 0 || claims.IssuedAt == 0 || claims.NotBefore == 0 {
+		return nil, ErrTokenMalformed
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrTokenWrongType
+	}
+	if !claims.VerifyIssuer(Config.JWT.Issuer, true) {
+		return nil, ErrTokenIssuer
+	}
+	if !claims.VerifyAudience(Config.JWT.Audience, true) {
+		return nil, ErrTokenAudience
+	}
+
+	return claims, nil
+}
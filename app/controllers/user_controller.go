@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/dialect"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserRepo struct {
+	DB *gorm.DB
+}
+
+type ReqUserEmail struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+var errUserNotFound = &service.Error{Message: "User not found", NotFound: true}
+
+func UserController(db *gorm.DB) *UserRepo {
+	return &UserRepo{DB: db}
+}
+
+// findTenantUser loads the user of the :id route parameter from the caller's
+// tenant, answering 404 or 500 itself when it can't.
+func findTenantUser(db *gorm.DB, c *gin.Context) (*models.User, bool) {
+	res := models.JsonResponse{Success: true}
+
+	userID, ok := idParam(c, errUserNotFound)
+	if !ok {
+		return nil, false
+	}
+
+	var user models.User
+	result := db.Scopes(tenantScope(c)).First(&user, userID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			errorMsg := "User not found"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusNotFound, res)
+			return nil, false
+		}
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// UnlockUser lifts a login lockout before its cooldown ends by clearing the
+// failed attempt counter of the user's email.
+func (repo *UserRepo) UnlockUser(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	err := resetLoginFailures(requestDB(c, repo.DB), user.Email)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "User unlocked successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// GetAllUsers lists the users of the tenant. search matches the email and
+// status is "active" or "disabled".
+func (repo *UserRepo) GetAllUsers(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	page := c.DefaultQuery("page", "1")
+	pageNum, _ := strconv.Atoi(page)
+	limit := c.DefaultQuery("limit", "10")
+	limitNum, _ := strconv.Atoi(limit)
+	search := c.DefaultQuery("search", "")
+	search = strings.ToLower(search)
+	status := c.DefaultQuery("status", "")
+
+	var users []models.User
+	query := requestDB(c, repo.DB).Scopes(tenantScope(c)).Model(&models.User{})
+
+	if search != "" {
+		query = query.Where(dialect.Of(requestDB(c, repo.DB)).ILike("email"), "%"+search+"%")
+	}
+	if status == "active" {
+		query = query.Where("disabled_at IS NULL")
+	} else if status == "disabled" {
+		query = query.Where("disabled_at IS NOT NULL")
+	}
+
+	var count int64
+	query.Count(&count)
+
+	query = query.Preload("Roles").Order("id").Offset((pageNum - 1) * limitNum).Limit(limitNum).Find(&users)
+	if query.Error != nil {
+		errorMsg := query.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = map[string]interface{}{
+		"users": users,
+		"count": count,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (repo *UserRepo) GetUserDetail(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	err := requestDB(c, repo.DB).Model(user).Association("Roles").Find(&user.Roles)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = user
+	c.JSON(http.StatusOK, res)
+}
+
+// isCurrentUser answers 400 when an admin targets their own account with an
+// action that would lock them out.
+func isCurrentUser(user *models.User, c *gin.Context) bool {
+	if user.ID != c.MustGet("user").(*models.User).ID {
+		return false
+	}
+
+	res := models.JsonResponse{Success: false}
+	errorMsg := "You can't do this to your own account"
+	res.Error = &errorMsg
+	c.JSON(http.StatusBadRequest, res)
+	return true
+}
+
+// DisableUser blocks the account and ends all its sessions. Access tokens and
+// API keys of the user are refused from the next request on.
+func (repo *UserRepo) DisableUser(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok || isCurrentUser(user, c) {
+		return
+	}
+
+	err := requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		if user.DisabledAt == nil {
+			if err := tx.Model(user).Update("disabled_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		return revokeAllSessions(tx, user)
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "User disabled successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+func (repo *UserRepo) EnableUser(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	result := requestDB(c, repo.DB).Model(user).Update("disabled_at", nil)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "User enabled successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// ForcePasswordReset signs the user out everywhere and refuses their logins
+// until they set a new password through the reset link mailed to them.
+func (repo *UserRepo) ForcePasswordReset(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok || isCurrentUser(user, c) {
+		return
+	}
+
+	err := requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+		return revokeAllSessions(tx, user)
+	})
+	if err == nil {
+		err = sendPasswordResetEmail(requestDB(c, repo.DB), *user)
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Password reset required, a reset link has been sent"
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateUserEmail changes the login email. The new address has to be verified
+// again and, as after a password change, the user is signed out everywhere.
+func (repo *UserRepo) UpdateUserEmail(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqUserEmail{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok {
+		return
+	}
+
+	var count int64
+	result := requestDB(c, repo.DB).Model(&models.User{}).Where("email = ? AND id <> ?", req.Email, user.ID).Count(&count)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+	if count > 0 {
+		errorMsg := "Email already exists"
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"email":             req.Email,
+			"email_verified_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := invalidateMailedTokens(tx, user); err != nil {
+			return err
+		}
+		return revokeAllSessions(tx, user)
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	// Like after Register, a mail failure doesn't undo the change.
+	if err := sendVerificationEmail(requestDB(c, repo.DB), *user); err != nil {
+		slog.ErrorContext(c.Request.Context(), "sending verification email", slog.Any("error", err))
+	}
+
+	res.Data = user
+	c.JSON(http.StatusOK, res)
+}
+
+// DeleteUser removes the account with its roles and recovery codes. Tokens,
+// sessions and keys go with it through their foreign keys.
+func (repo *UserRepo) DeleteUser(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+
+	user, ok := findTenantUser(requestDB(c, repo.DB), c)
+	if !ok || isCurrentUser(user, c) {
+		return
+	}
+
+	result := requestDB(c, repo.DB).Select(clause.Associations).Delete(user)
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "User deleted successfully"
+	c.JSON(http.StatusOK, res)
+}
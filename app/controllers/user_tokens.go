@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fajaaro/dbo/app/mailer"
+	"github.com/fajaaro/dbo/app/models"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+// Mailer delivers verification and password reset mail. main swaps in the
+// implementation selected by MAIL_DRIVER.
+var Mailer mailer.Mailer = mailer.NewFileMailer("outbox", "no-reply@dbo.local")
+
+var errUserTokenUsed = &TokenError{Code: "token_used", Message: "Token has already been used"}
+
+type ReqEmail struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ReqToken struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ReqPasswordReset struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func appURL() string {
+	return Config.Auth.AppURL
+}
+
+func requireEmailVerification() bool {
+	return Config.Auth.RequireEmailVerification
+}
+
+// issueUserToken signs a single-use token for purpose and records its jti.
+func issueUserToken(db *gorm.DB, user models.User, purpose string, ttl time.Duration) (string, error) {
+	claims := newTokenClaims(purpose, time.Now().Add(ttl), user)
+	claims.Email = user.Email
+	record := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		JTI:       claims.Id,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}
+	if err := db.Create(record).Error; err != nil {
+		return "", err
+	}
+
+	return signToken(claims)
+}
+
+// consumeUserToken validates a token issued by issueUserToken and marks it
+// used, returning its user.
+func consumeUserToken(db *gorm.DB, token string, purpose string) (*models.User, error) {
+	record, err := findUserToken(db, token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	err = markUserTokenUsed(db, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record.User, nil
+}
+
+// findUserToken validates a token issued by issueUserToken without using it
+// up. The record comes with its user and the user's roles.
+func findUserToken(db *gorm.DB, token string, purpose string) (*models.UserToken, error) {
+	claims, err := parseToken(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.UserToken
+	result := db.Preload("User.Roles").Where("jti = ? AND purpose = ?", claims.Id, purpose).First(&record)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, ErrTokenRevoked
+		}
+		return nil, result.Error
+	}
+	if record.UsedAt != nil {
+		return nil, errUserTokenUsed
+	}
+	// A token mailed to an address the user has since moved away from is no
+	// longer theirs to use.
+	if claims.Subject != tokenSubject(record.UserID) || claims.Email != record.User.Email {
+		return nil, ErrTokenRevoked
+	}
+
+	return &record, nil
+}
+
+func markUserTokenUsed(db *gorm.DB, record *models.UserToken) error {
+	result := db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errUserTokenUsed
+	}
+	return nil
+}
+
+func sendVerificationEmail(db *gorm.DB, user models.User) error {
+	token, err := issueUserToken(db, user, TokenTypeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Open the link below to verify your email address. It expires in 24 hours.\n\n" +
+			appURL() + "/verify-email?token=" + url.QueryEscape(token) + "\n\n" +
+			"Verification token: " + token + "\n",
+	})
+}
+
+func sendPasswordResetEmail(db *gorm.DB, user models.User) error {
+	token, err := issueUserToken(db, user, TokenTypePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Open the link below to choose a new password. It expires in 1 hour.\n" +
+			"If you did not ask for this, you can ignore this email.\n\n" +
+			appURL() + "/reset-password?token=" + url.QueryEscape(token) + "\n\n" +
+			"Reset token: " + token + "\n",
+	})
+}
+
+// invalidateMailedTokens uses up the verification and password reset tokens
+// of user that are still pending, as when the email they were mailed to
+// stops being the user's.
+func invalidateMailedTokens(db *gorm.DB, user *models.User) error {
+	return db.Model(&models.UserToken{}).
+		Where("user_id = ? AND used_at IS NULL AND purpose IN ?", user.ID, []string{TokenTypeEmailVerification, TokenTypePasswordReset}).
+		Update("used_at", time.Now()).Error
+}
+
+// revokeAllSessions revokes every refresh token of user and makes
+// ValidateAccessToken reject access tokens issued before now.
+func revokeAllSessions(db *gorm.DB, user *models.User) error {
+	now := time.Now()
+	err := db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	err = db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(user).Update("tokens_revoked_at", now).Error
+}
+
+// RequestEmailVerification mails a new verification link. It answers the same
+// way for unknown and already verified emails so accounts can't be probed.
+func (repo *AuthRepo) RequestEmailVerification(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqEmail{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	var user models.User
+	result := requestDB(c, repo.DB).Where("email = ?", req.Email).First(&user)
+	if result.Error == nil && user.EmailVerifiedAt == nil {
+		err = sendVerificationEmail(requestDB(c, repo.DB), user)
+	} else if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		err = result.Error
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "If the email is registered and not verified yet, a verification link has been sent"
+	c.JSON(http.StatusOK, res)
+}
+
+func (repo *AuthRepo) ConfirmEmailVerification(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqToken{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	user, err := consumeUserToken(requestDB(c, repo.DB), req.Token, TokenTypeEmailVerification)
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
+		c.JSON(tokenErrorStatus(err), res)
+		return
+	}
+
+	result := requestDB(c, repo.DB).Model(user).Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		errorMsg := result.Error.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Email verified successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// RequestPasswordReset mails a password reset link. Like
+// RequestEmailVerification it never reveals whether the email exists.
+func (repo *AuthRepo) RequestPasswordReset(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqEmail{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	var user models.User
+	result := requestDB(c, repo.DB).Where("email = ?", req.Email).First(&user)
+	if result.Error == nil {
+		err = sendPasswordResetEmail(requestDB(c, repo.DB), user)
+	} else if result.Error != gorm.ErrRecordNotFound {
+		err = result.Error
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "If the email is registered, a password reset link has been sent"
+	c.JSON(http.StatusOK, res)
+}
+
+// ConfirmPasswordReset sets a new password and signs the user out everywhere.
+// Receiving the mail proves the address, so it is marked verified as well.
+func (repo *AuthRepo) ConfirmPasswordReset(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	res := models.JsonResponse{Success: true}
+	req := ReqPasswordReset{}
+	err := c.BindJSON(&req)
+	if err != nil {
+		handleValidationError(err, c)
+		return
+	}
+
+	// The token is only used up once the new password passed the policy, so
+	// the user can try again with the same link.
+	record, err := findUserToken(requestDB(c, repo.DB), req.Token, TokenTypePasswordReset)
+	if err == nil && !validatePassword(req.Password, record.User.Email, c) {
+		return
+	}
+	if err == nil {
+		err = markUserTokenUsed(requestDB(c, repo.DB), record)
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		res.Code = tokenErrorCode(err)
+		c.JSON(tokenErrorStatus(err), res)
+		return
+	}
+	user := &record.User
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		res.Success = false
+		errorMsg := "Failed to hash password"
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	err = requestDB(c, repo.DB).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"password":                string(hashedPassword),
+			"password_reset_required": false,
+		}
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Any other reset link that is still in a mailbox dies with this one.
+		err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, TokenTypePasswordReset).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return revokeAllSessions(tx, user)
+	})
+	if err != nil {
+		errorMsg := err.Error()
+		res.Success = false
+		res.Error = &errorMsg
+		c.JSON(http.StatusInternalServerError, res)
+		return
+	}
+
+	res.Data = "Password has been reset successfully"
+	c.JSON(http.StatusOK, res)
+}
+
+// sendVerificationEmailAfterRegister is best effort: a mail failure must not
+// fail the registration, the user can ask for a new link.
+func sendVerificationEmailAfterRegister(db *gorm.DB, user models.User) {
+	if err := sendVerificationEmail(db, user); err != nil {
+		slog.ErrorContext(db.Statement.Context, "sending verification email", slog.Any("error", err))
+	}
+}
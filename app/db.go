@@ -2,38 +2,108 @@ package app
 
 import (
 	"fmt"
-	"os"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/fajaaro/dbo/app/config"
+	"github.com/fajaaro/dbo/app/logging"
+
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var db *gorm.DB
+// DSN builds the connection string of cfg.Driver. For sqlite the database
+// name is the path of the database file.
+func DSN(cfg config.DBConfig) string {
+	switch cfg.Driver {
+	case "mysql":
+		mysqlConfig := mysqldriver.NewConfig()
+		mysqlConfig.User = cfg.Username
+		mysqlConfig.Passwd = cfg.Password
+		mysqlConfig.Net = "tcp"
+		mysqlConfig.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+		mysqlConfig.DBName = cfg.Name
+		mysqlConfig.ParseTime = true
+		mysqlConfig.Loc, _ = time.LoadLocation(cfg.TimeZone)
+		// Migration files hold several statements each.
+		mysqlConfig.MultiStatements = true
+		mysqlConfig.Params = map[string]string{"charset": "utf8mb4"}
+		return mysqlConfig.FormatDSN()
+	case "sqlite":
+		separator := "?"
+		if strings.Contains(cfg.Name, "?") {
+			separator = "&"
+		}
+		// Foreign keys are off by default in SQLite, and the busy timeout
+		// makes concurrent writers wait for each other instead of failing.
+		return cfg.Name + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	default:
+		return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+			cfg.Host, cfg.Username, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode, cfg.TimeZone)
+	}
+}
 
-func InitDb() *gorm.DB {
-	db = connectDB()
-	return db
+// Dialector returns the GORM dialector of cfg.Driver.
+func Dialector(cfg config.DBConfig) gorm.Dialector {
+	switch cfg.Driver {
+	case "mysql":
+		return mysql.Open(DSN(cfg))
+	case "sqlite":
+		return sqlite.Open(DSN(cfg))
+	default:
+		return postgres.Open(DSN(cfg))
+	}
 }
 
-func GetDb() *gorm.DB {
-	return db
+// describe names the database of cfg for logs, leaving out the password.
+func describe(cfg config.DBConfig) string {
+	if cfg.Driver == "sqlite" {
+		return "sqlite database " + cfg.Name
+	}
+	return fmt.Sprintf("%s database %s at %s:%d", cfg.Driver, cfg.Name, cfg.Host, cfg.Port)
 }
 
-func connectDB() *gorm.DB {
-	var DB_USERNAME = os.Getenv("DB_USERNAME")
-	var DB_PASSWORD = os.Getenv("DB_PASSWORD")
-	var DB_NAME = os.Getenv("DB_NAME")
-	var DB_HOST = os.Getenv("DB_HOST")
-	var DB_PORT = os.Getenv("DB_PORT")
+// Connect opens the one database handle the whole app shares and sizes its
+// connection pool. While the database is unreachable it retries with
+// exponential backoff for up to cfg.ConnectTimeout, so the API can start
+// alongside its database in docker-compose.
+func Connect(cfg config.DBConfig) (*gorm.DB, error) {
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	backoff := 500 * time.Millisecond
 
-	conn := "host=" + DB_HOST + " user=" + DB_USERNAME + " password=" + DB_PASSWORD + " dbname=" + DB_NAME + " port=" + DB_PORT + " sslmode=disable TimeZone=Asia/Jakarta"
-	fmt.Println("conn : ", conn)
-	db, err := gorm.Open(postgres.Open(conn), &gorm.Config{})
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(Dialector(cfg), &gorm.Config{Logger: logging.NewGormLogger()})
+		if err == nil {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return nil, err
+			}
+			sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+			sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+			sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+			sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+			return db, nil
+		}
 
-	if err != nil {
-		fmt.Println("Error connecting to database : error=" + err.Error())
-		return nil
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("connecting to %s: %w", describe(cfg), err)
+		}
+		slog.Warn("database not reachable, retrying",
+			slog.String("database", describe(cfg)),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
 	}
-
-	return db
 }
@@ -0,0 +1,65 @@
+package denylist
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DatabaseStore struct {
+	DB   *gorm.DB
+	done chan struct{}
+}
+
+// NewDatabaseStore returns a Store shared by every replica using db. Expired
+// rows are deleted every interval.
+func NewDatabaseStore(db *gorm.DB, interval time.Duration) *DatabaseStore {
+	s := &DatabaseStore{
+		DB:   db,
+		done: make(chan struct{}),
+	}
+	go s.purgeLoop(interval)
+	return s
+}
+
+func (s *DatabaseStore) Add(jti string, expiresAt time.Time) error {
+	entry := models.RevokedAccessToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+	}
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
+}
+
+func (s *DatabaseStore) Contains(jti string) (bool, error) {
+	var count int64
+	result := s.DB.Model(&models.RevokedAccessToken{}).
+		Where("jti = ? AND expires_at > ?", jti, time.Now()).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+func (s *DatabaseStore) Close() {
+	close(s.done)
+}
+
+func (s *DatabaseStore) purgeLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			result := s.DB.Where("expires_at <= ?", time.Now()).Delete(&models.RevokedAccessToken{})
+			if result.Error != nil {
+				slog.Error("purging token denylist", slog.Any("error", result.Error))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package denylist
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const purgeInterval = time.Minute
+
+// Store keeps the IDs (jti) of access tokens that were revoked before they
+// expired. Entries only need to live until the token's own expiry.
+type Store interface {
+	Add(jti string, expiresAt time.Time) error
+	Contains(jti string) (bool, error)
+}
+
+// New returns the Store for driver, which is "memory" (the default) or
+// "database", the database of db whatever its dialect. "postgres" is the old
+// name of database. Use database when running more than one API replica.
+func New(driver string, db *gorm.DB) (Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(purgeInterval), nil
+	case "database", "postgres":
+		return NewDatabaseStore(db, purgeInterval), nil
+	default:
+		return nil, fmt.Errorf("unknown token denylist driver %q", driver)
+	}
+}
@@ -0,0 +1,65 @@
+package denylist
+
+import (
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+	done    chan struct{}
+}
+
+// NewMemoryStore returns a process-local Store that evicts expired entries
+// every interval.
+func NewMemoryStore(interval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		entries: map[string]time.Time{},
+		done:    make(chan struct{}),
+	}
+	go s.purgeLoop(interval)
+	return s
+}
+
+func (s *MemoryStore) Add(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryStore) Contains(jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	expiresAt, ok := s.entries[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryStore) Close() {
+	close(s.done)
+}
+
+func (s *MemoryStore) purgeLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.purge()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) purge() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for jti, expiresAt := range s.entries {
+		if !now.Before(expiresAt) {
+			delete(s.entries, jti)
+		}
+	}
+}
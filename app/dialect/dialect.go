@@ -0,0 +1,102 @@
+package dialect
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Dialect is the SQL that differs between the databases dbo runs on. Code
+// that can't be written portably goes through it, so every backend behaves
+// the same. Schema DDL, check constraints included, isn't part of it: each
+// dialect has its own migration files under app/migrations/sql.
+type Dialect interface {
+	// Name is the DB_DRIVER of the database: postgres, mysql or sqlite.
+	Name() string
+	// ILike returns a condition matching column against a LIKE pattern,
+	// given as its one ? argument, ignoring case.
+	ILike(column string) string
+	// Lock waits for the lock numbered key and holds it for the session of
+	// conn until Unlock. It guards work that must not run concurrently
+	// across replicas, such as migrations.
+	Lock(conn *gorm.DB, key int64) error
+	Unlock(conn *gorm.DB, key int64) error
+}
+
+// Of returns the Dialect of db.
+func Of(db *gorm.DB) Dialect {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return MySQL{}
+	case "sqlite":
+		return SQLite{}
+	default:
+		return Postgres{}
+	}
+}
+
+type Postgres struct{}
+
+func (Postgres) Name() string { return "postgres" }
+
+func (Postgres) ILike(column string) string {
+	return column + " ILIKE ?"
+}
+
+func (Postgres) Lock(conn *gorm.DB, key int64) error {
+	return conn.Exec("SELECT pg_advisory_lock(?)", key).Error
+}
+
+func (Postgres) Unlock(conn *gorm.DB, key int64) error {
+	return conn.Exec("SELECT pg_advisory_unlock(?)", key).Error
+}
+
+type MySQL struct{}
+
+func (MySQL) Name() string { return "mysql" }
+
+// ILike lowers both sides rather than relying on the column's collation,
+// which may be case-sensitive.
+func (MySQL) ILike(column string) string {
+	return "LOWER(" + column + ") LIKE LOWER(?)"
+}
+
+func lockName(key int64) string {
+	return fmt.Sprintf("dbo_%d", key)
+}
+
+func (MySQL) Lock(conn *gorm.DB, key int64) error {
+	var acquired *int
+	err := conn.Raw("SELECT GET_LOCK(?, -1)", lockName(key)).Scan(&acquired).Error
+	if err != nil {
+		return err
+	}
+	if acquired == nil || *acquired != 1 {
+		return fmt.Errorf("could not take lock %s", lockName(key))
+	}
+	return nil
+}
+
+func (MySQL) Unlock(conn *gorm.DB, key int64) error {
+	return conn.Exec("SELECT RELEASE_LOCK(?)", lockName(key)).Error
+}
+
+type SQLite struct{}
+
+func (SQLite) Name() string { return "sqlite" }
+
+// ILike lowers both sides: SQLite's LIKE already ignores case, but only
+// when case_sensitive_like is off.
+func (SQLite) ILike(column string) string {
+	return "LOWER(" + column + ") LIKE LOWER(?)"
+}
+
+// Lock does nothing. A SQLite database is a file used by one process, and
+// SQLite serializes writers itself.
+func (SQLite) Lock(conn *gorm.DB, key int64) error {
+	return nil
+}
+
+func (SQLite) Unlock(conn *gorm.DB, key int64) error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package dialect
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		dialector gorm.Dialector
+		want      Dialect
+	}{
+		{postgres.New(postgres.Config{}), Postgres{}},
+		{mysql.New(mysql.Config{}), MySQL{}},
+		{sqlite.Open(":memory:"), SQLite{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialector.Name(), func(t *testing.T) {
+			got := Of(&gorm.DB{Config: &gorm.Config{Dialector: tt.dialector}})
+			if got != tt.want {
+				t.Fatalf("got %T, want %T", got, tt.want)
+			}
+			if got.Name() != tt.dialector.Name() {
+				t.Errorf("got name %q, want %q", got.Name(), tt.dialector.Name())
+			}
+		})
+	}
+}
+
+func TestILike(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres{}, "email ILIKE ?"},
+		{MySQL{}, "LOWER(email) LIKE LOWER(?)"},
+		{SQLite{}, "LOWER(email) LIKE LOWER(?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			if got := tt.dialect.ILike("email"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestILikeIgnoresCase runs the conditions that are plain SQL, those of MySQL
+// and SQLite, on SQLite with case_sensitive_like on.
+func TestILikeIgnoresCase(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	type user struct {
+		ID    uint
+		Email string
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]user{{Email: "Alice@example.com"}, {Email: "ALICE@example.org"}, {Email: "bob@example.com"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("PRAGMA case_sensitive_like = ON").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dialect := range []Dialect{MySQL{}, SQLite{}} {
+		t.Run(dialect.Name(), func(t *testing.T) {
+			var count int64
+			if err := db.Model(&user{}).Where(dialect.ILike("email"), "%aLiCe%").Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if count != 2 {
+				t.Errorf("matched %d rows, want 2", count)
+			}
+		})
+	}
+}
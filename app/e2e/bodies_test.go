@@ -0,0 +1,47 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestMalformedBodies sends bodies that aren't valid JSON, or hold a value of
+// the wrong type, to routes that bind them. Each is answered with a 400.
+func TestMalformedBodies(t *testing.T) {
+	h := NewHarness(t)
+	h.Register("admin@example.com")
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/api/api-keys", `{"name":`},
+		{http.MethodPost, "/api/api-keys", `{"name": "CI", "scopes": ["customers:read"], "expires_at": "tomorrow"}`},
+		{http.MethodPut, "/api/api-keys/1", `{"name": 42}`},
+		{http.MethodPost, "/api/oauth/clients", `{"name": "Reports"`},
+		{http.MethodPost, "/api/oauth/clients", `{"name": "Reports", "redirect_uris": "https://example.com/callback"}`},
+		{http.MethodPost, "/api/auth/mfa/confirm", `{"code": 123456}`},
+		{http.MethodPost, "/api/auth/mfa/verify", `{"mfa_token": "x", "code": `},
+		{http.MethodPost, "/api/auth/mfa/disable", `{"password": ["x"], "code": "123456"}`},
+		{http.MethodPost, "/api/auth/verify-email/request", `{"email": `},
+		{http.MethodPost, "/api/auth/verify-email/confirm", `{"token": true}`},
+		{http.MethodPost, "/api/auth/password-reset/request", `not json`},
+		{http.MethodPost, "/api/auth/password-reset/confirm", `{"token": "x", "password": 12345678901234}`},
+		{http.MethodPost, "/api/users/1/roles", `{"role": `},
+		{http.MethodPost, "/api/tenant/users", `{"email": "bob@example.com", "password": 42}`},
+		{http.MethodPut, "/api/users/1/email", `{"email": `},
+		{http.MethodPatch, "/api/me", `{"email": ["admin@example.com"]}`},
+		{http.MethodPost, "/api/me/password", `{"current_password": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.body, func(t *testing.T) {
+			h := h.WithT(t)
+			token := h.Login("admin@example.com").AccessToken
+			res := h.Call(tt.method, tt.path, token, tt.body).Expect(t, http.StatusBadRequest)
+			if msg := res.ErrorMessage(); msg != "invalid request body" {
+				t.Errorf("got error %q, want %q", msg, "invalid request body")
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fajaaro/dbo/app"
+	"github.com/fajaaro/dbo/app/config"
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/denylist"
+	"github.com/fajaaro/dbo/app/keys"
+	"github.com/fajaaro/dbo/app/logging"
+	"github.com/fajaaro/dbo/app/mailer"
+	"github.com/fajaaro/dbo/app/metrics"
+	"github.com/fajaaro/dbo/app/migrations"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/routers"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testPassword = "horse-battery-42"
+
+// server is the Postgres server every test creates its database on, or nil
+// to use SQLite. It is set up once by TestMain.
+var server *postgresServer
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var err error
+	server, err = startServer(os.Getenv("E2E_DB"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "e2e:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	if server != nil {
+		server.stop()
+	}
+	os.Exit(code)
+}
+
+// Harness is the API running on httptest against a database of its own.
+// The controllers keep their dependencies in package variables, so tests
+// using a Harness can't run in parallel.
+type Harness struct {
+	T      *testing.T
+	DB     *gorm.DB
+	Server *httptest.Server
+}
+
+// Response is a decoded models.JsonResponse with its status and headers.
+type Response struct {
+	models.JsonResponse
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Tokens are the tokens a login returns.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+var databases int64
+
+// NewHarness starts the API on a new, migrated database with the default
+// configuration. Login delays are switched off, metrics are served with the
+// API and mail is kept in the outbox_messages table.
+func NewHarness(t *testing.T) *Harness {
+	t.Helper()
+
+	db := newDatabase(t)
+
+	cfg := config.Default()
+	cfg.JWT.SecretKey = "e2e-secret-key-that-is-long-enough"
+	cfg.Login.DelayBase = 0
+	cfg.Mail.Driver = "db"
+	cfg.Metrics.Enabled = true
+	controllers.Config = cfg
+	controllers.SigningKeys = keys.NewHMACManager([]byte(cfg.JWT.SecretKey))
+
+	tokenDenylist := denylist.NewMemoryStore(time.Minute)
+	t.Cleanup(tokenDenylist.Close)
+	controllers.TokenDenylist = tokenDenylist
+
+	mail, err := mailer.New(cfg.Mail, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllers.Mailer = mail
+
+	// Like serve, with the business gauges on so they are covered too.
+	if err := metrics.InstrumentDB(db, "e2e", true); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Harness{
+		T:      t,
+		DB:     db,
+		Server: httptest.NewServer(routers.SetupRouter(db)),
+	}
+	t.Cleanup(h.Server.Close)
+	return h
+}
+
+func newDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormConfig := &gorm.Config{Logger: logging.NewGormLogger()}
+
+	if server != nil {
+		name := fmt.Sprintf("dbo_e2e_%d_%d", os.Getpid(), atomic.AddInt64(&databases, 1))
+		db, err := server.createDatabase(name, gormConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if err := server.dropDatabase(name, db); err != nil {
+				t.Error(err)
+			}
+		})
+		if err := migrations.Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	dbConfig := config.Default().DB
+	dbConfig.Driver = "sqlite"
+	dbConfig.Name = fmt.Sprintf("file:e2e%d?mode=memory&cache=shared", atomic.AddInt64(&databases, 1))
+	db, err := gorm.Open(app.Dialector(dbConfig), gormConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := migrations.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Reload serves the API again on the same database, with a router built
+// from the current controllers.Config, for settings SetupRouter reads.
+func (h *Harness) Reload() {
+	server := httptest.NewServer(routers.SetupRouter(h.DB))
+	h.T.Cleanup(server.Close)
+	h.Server = server
+}
+
+// WithT returns a copy of h that reports to t, for use in subtests.
+func (h *Harness) WithT(t *testing.T) *Harness {
+	copy := *h
+	copy.T = t
+	return &copy
+}
+
+// Call sends a request with body encoded as JSON, unless it is a string,
+// which is sent as is. token, when set, is sent as a bearer token.
+func (h *Harness) Call(method string, path string, token string, body interface{}) *Response {
+	h.T.Helper()
+
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.T.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, h.Server.URL+path, reader)
+	if err != nil {
+		h.T.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.Do(req)
+}
+
+// Do sends req and decodes the response.
+func (h *Harness) Do(req *http.Request) *Response {
+	h.T.Helper()
+
+	httpRes, err := h.Server.Client().Do(req)
+	if err != nil {
+		h.T.Fatal(err)
+	}
+	defer httpRes.Body.Close()
+
+	res := &Response{Status: httpRes.StatusCode, Header: httpRes.Header}
+	res.Body, err = io.ReadAll(httpRes.Body)
+	if err != nil {
+		h.T.Fatal(err)
+	}
+	if strings.HasPrefix(httpRes.Header.Get("Content-Type"), "application/json") {
+		// Not every route answers with a JsonResponse, e.g. the JWKS.
+		_ = json.Unmarshal(res.Body, &res.JsonResponse)
+	}
+	return res
+}
+
+// Register signs up email with the test password, which makes it the admin
+// of a new tenant, and returns the response data.
+func (h *Harness) Register(email string) map[string]interface{} {
+	h.T.Helper()
+
+	res := h.Call(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    email,
+		"password": testPassword,
+	})
+	res.Expect(h.T, http.StatusCreated)
+	return res.Map()
+}
+
+// Login logs email in with the test password.
+func (h *Harness) Login(email string) Tokens {
+	h.T.Helper()
+
+	res := h.Call(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    email,
+		"password": testPassword,
+	})
+	res.Expect(h.T, http.StatusOK)
+	data := res.Map()
+	return Tokens{
+		AccessToken:  data["access_token"].(string),
+		RefreshToken: data["refresh_token"].(string),
+	}
+}
+
+// SignUp registers email and returns its access token.
+func (h *Harness) SignUp(email string) string {
+	h.T.Helper()
+
+	h.Register(email)
+	return h.Login(email).AccessToken
+}
+
+// MailedToken returns the token on the line starting with label, e.g.
+// "Reset token: ", of the last mail to email that has one.
+func (h *Harness) MailedToken(email string, label string) string {
+	h.T.Helper()
+
+	var messages []models.OutboxMessage
+	if err := h.DB.Where(&models.OutboxMessage{To: email}).Order("id DESC").Find(&messages).Error; err != nil {
+		h.T.Fatal(err)
+	}
+	for _, message := range messages {
+		for _, line := range strings.Split(message.Body, "\n") {
+			if strings.HasPrefix(line, label) {
+				return strings.TrimPrefix(line, label)
+			}
+		}
+	}
+	h.T.Fatalf("no mail to %s with %q", email, label)
+	return ""
+}
+
+// Expect fails the test unless the response has status.
+func (res *Response) Expect(t *testing.T, status int) *Response {
+	t.Helper()
+
+	if res.Status != status {
+		t.Fatalf("got status %d, want %d: %s", res.Status, status, res.Body)
+	}
+	return res
+}
+
+// Map returns the data of the response as an object.
+func (res *Response) Map() map[string]interface{} {
+	data, _ := res.Data.(map[string]interface{})
+	return data
+}
+
+// Lookup returns the value at a dotted path in the response body, e.g.
+// "data.access_token", or nil.
+func (res *Response) Lookup(path string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(res.Body, &value); err != nil {
+		return nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// ErrorMessage returns the error of the response, or "".
+func (res *Response) ErrorMessage() string {
+	if res.Error == nil {
+		return ""
+	}
+	return *res.Error
+}
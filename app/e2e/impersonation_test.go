@@ -0,0 +1,43 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestImpersonationCantManageAccounts impersonates another admin of the
+// tenant. The token works for the admin's data, but not for role and user
+// management.
+func TestImpersonationCantManageAccounts(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+	bobID := h.Call(http.MethodPost, "/api/tenant/users", alice, map[string]string{
+		"email":    "bob@example.com",
+		"password": testPassword,
+		"role":     "admin",
+	}).Expect(t, http.StatusCreated).Map()["user_id"]
+
+	asBob := h.Call(http.MethodPost, fmt.Sprintf("/api/users/%v/impersonate", bobID), alice, nil).
+		Expect(t, http.StatusOK).Map()["access_token"].(string)
+
+	h.Call(http.MethodGet, "/api/customers", asBob, nil).Expect(t, http.StatusOK)
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodGet, "/api/roles", nil},
+		{http.MethodPost, fmt.Sprintf("/api/users/%v/roles", bobID), map[string]string{"role": "viewer"}},
+		{http.MethodGet, "/api/users", nil},
+		{http.MethodPost, fmt.Sprintf("/api/users/%v/disable", bobID), nil},
+		{http.MethodPost, "/api/tenant/users", map[string]string{"email": "carol@example.com", "password": testPassword}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res := h.Call(tt.method, tt.path, asBob, tt.body).Expect(t, http.StatusForbidden)
+			expectValue(t, res, "code", "impersonation_not_allowed")
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/controllers"
+)
+
+// TestLoginEmailIgnoresCase logs in with spellings of an email that differ
+// in case. They reach the same account and count towards the same lockout.
+func TestLoginEmailIgnoresCase(t *testing.T) {
+	h := NewHarness(t)
+	h.Register("Alice@example.com")
+
+	login := func(email string, password string) *Response {
+		return h.Call(http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":    email,
+			"password": password,
+		})
+	}
+
+	login("alice@EXAMPLE.com", testPassword).Expect(t, http.StatusOK)
+
+	for i := 0; i < controllers.Config.Login.MaxFailures; i++ {
+		login("ALICE@example.com", "wrong-password").Expect(t, http.StatusBadRequest)
+	}
+	res := login("Alice@example.com", testPassword).Expect(t, http.StatusTooManyRequests)
+	expectValue(t, res, "code", "account_locked")
+}
@@ -0,0 +1,112 @@
+package e2e
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/config"
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// counted returns how far counter moves while fn runs. The metrics are
+// shared by every test of the process, so tests compare before and after.
+func counted(counter prometheus.Collector, fn func()) float64 {
+	before := testutil.ToFloat64(counter)
+	fn()
+	return testutil.ToFloat64(counter) - before
+}
+
+func TestMetricsCountLoginsAndRefreshes(t *testing.T) {
+	h := NewHarness(t)
+	h.Register("alice@example.com")
+
+	var tokens Tokens
+	if n := counted(metrics.LoginSuccesses, func() { tokens = h.Login("alice@example.com") }); n != 1 {
+		t.Errorf("login successes went up by %v, want 1", n)
+	}
+
+	invalid := metrics.LoginFailures.WithLabelValues(metrics.LoginInvalidCredentials)
+	n := counted(invalid, func() {
+		h.Call(http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":    "alice@example.com",
+			"password": "wrong-" + testPassword,
+		}).Expect(t, http.StatusBadRequest)
+	})
+	if n != 1 {
+		t.Errorf("invalid credential failures went up by %v, want 1", n)
+	}
+
+	refreshed := metrics.TokenRefreshes.WithLabelValues("success")
+	reused := metrics.TokenRefreshes.WithLabelValues("refresh_token_reused")
+	n = counted(refreshed, func() {
+		h.Call(http.MethodPost, "/api/auth/refresh-token", "", map[string]string{
+			"refresh_token": tokens.RefreshToken,
+		}).Expect(t, http.StatusOK)
+	})
+	if n != 1 {
+		t.Errorf("successful refreshes went up by %v, want 1", n)
+	}
+	n = counted(reused, func() {
+		h.Call(http.MethodPost, "/api/auth/refresh-token", "", map[string]string{
+			"refresh_token": tokens.RefreshToken,
+		}).Expect(t, http.StatusUnauthorized)
+	})
+	if n != 1 {
+		t.Errorf("reused refreshes went up by %v, want 1", n)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	h := NewHarness(t)
+	token := h.SignUp("alice@example.com")
+
+	customer := h.Call(http.MethodPost, "/api/customers", token, map[string]string{
+		"name":         "Cust 1",
+		"email":        "cust1@yopmail.com",
+		"phone_number": "087786552751",
+		"gender":       "female",
+	}).Expect(t, http.StatusCreated).Map()
+	h.Call(http.MethodPost, "/api/orders", token, map[string]interface{}{
+		"customer_id":    customer["id"],
+		"product_name":   "Laptop",
+		"quantity":       1,
+		"total_price":    100,
+		"payment_status": "paid",
+	}).Expect(t, http.StatusCreated)
+	h.Call(http.MethodGet, "/api/orders/999999", token, nil).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodGet, "/no/such/route/42", "", nil).Expect(t, http.StatusNotFound)
+
+	res := h.Call(http.MethodGet, "/metrics", "", nil).Expect(t, http.StatusOK)
+	body := string(res.Body)
+	for _, want := range []string{
+		`dbo_http_requests_total{method="GET",route="/api/orders/:id",status="404"}`,
+		`dbo_http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`dbo_http_request_duration_seconds_bucket{method="POST",route="/api/orders",status="201",le="+Inf"}`,
+		`dbo_login_successes_total`,
+		`dbo_db_query_duration_seconds_count{operation="create"}`,
+		`dbo_db_query_duration_seconds_count{operation="query"}`,
+		`go_sql_open_connections{db_name="e2e"}`,
+		`go_sql_wait_count_total{db_name="e2e"}`,
+		`dbo_orders{payment_status="paid"} 1`,
+		`dbo_customers 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %s", want)
+		}
+	}
+	if strings.Contains(body, "/no/such/route") || strings.Contains(body, "/api/orders/999999") {
+		t.Errorf("metrics are labeled with request paths:\n%s", body)
+	}
+}
+
+func TestMetricsAreOffByDefault(t *testing.T) {
+	h := NewHarness(t)
+	controllers.Config.Metrics = config.Default().Metrics
+	h.Reload()
+
+	h.Call(http.MethodGet, "/metrics", "", nil).Expect(t, http.StatusNotFound)
+}
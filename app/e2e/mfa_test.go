@@ -0,0 +1,49 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// TestTOTPCodesWorkOnce replays authenticator codes: the one that confirmed
+// enrollment and one that already logged in. Both are refused, while a code
+// of a later time step still works.
+func TestTOTPCodesWorkOnce(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+
+	secret := h.Call(http.MethodPost, "/api/auth/mfa/enroll", alice, nil).Expect(t, http.StatusOK).Map()["secret"].(string)
+	now := time.Now()
+	confirmCode, err := totp.GenerateCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Call(http.MethodPost, "/api/auth/mfa/confirm", alice, map[string]string{
+		"code": confirmCode,
+	}).Expect(t, http.StatusOK)
+
+	verify := func(code string) *Response {
+		t.Helper()
+		mfaToken := h.Call(http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":    "alice@example.com",
+			"password": testPassword,
+		}).Expect(t, http.StatusOK).Map()["mfa_token"].(string)
+		return h.Call(http.MethodPost, "/api/auth/mfa/verify", "", map[string]string{
+			"mfa_token": mfaToken,
+			"code":      code,
+		})
+	}
+
+	expectValue(t, verify(confirmCode).Expect(t, http.StatusBadRequest), "code", "mfa_invalid_code")
+
+	// The next step is still accepted for clock drift.
+	nextCode, err := totp.GenerateCode(secret, now.Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(nextCode).Expect(t, http.StatusOK)
+	expectValue(t, verify(nextCode).Expect(t, http.StatusBadRequest), "code", "mfa_invalid_code")
+}
@@ -0,0 +1,64 @@
+package e2e
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestIntrospectOnlyOwnTokens has two confidential clients introspect each
+// other's tokens and a user's login token. Only a client's own tokens are
+// reported as active.
+func TestIntrospectOnlyOwnTokens(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+
+	type client struct{ id, secret string }
+	newClient := func(name string) client {
+		data := h.Call(http.MethodPost, "/api/oauth/clients", alice, map[string]interface{}{
+			"name":         name,
+			"scopes":       []string{"customers:read"},
+			"confidential": true,
+		}).Expect(t, http.StatusCreated).Map()
+		return client{
+			id:     data["client"].(map[string]interface{})["client_id"].(string),
+			secret: data["client_secret"].(string),
+		}
+	}
+	post := func(path string, as client, form url.Values) *Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, h.Server.URL+path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(as.id, as.secret)
+		return h.Do(req).Expect(t, http.StatusOK)
+	}
+
+	reports := newClient("Reports")
+	billing := newClient("Billing")
+	token := post("/oauth/token", reports, url.Values{"grant_type": {"client_credentials"}}).Lookup("access_token").(string)
+
+	tests := []struct {
+		name       string
+		as         client
+		token      string
+		wantActive bool
+	}{
+		{"own token", reports, token, true},
+		{"another client's token", billing, token, false},
+		{"a user's login token", reports, alice, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := post("/oauth/introspect", tt.as, url.Values{"token": {tt.token}})
+			if active := res.Lookup("active"); active != tt.wantActive {
+				t.Fatalf("active is %v, want %v: %s", active, tt.wantActive, res.Body)
+			}
+			if tt.wantActive {
+				expectValue(t, res, "client_id", reports.id)
+			} else if res.Lookup("sub") != nil {
+				t.Errorf("inactive answer gives more than active: %s", res.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package e2e
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// postgresServer is where the tests create their databases: either the
+// server in E2E_DATABASE_URL or a cluster started for the test run.
+type postgresServer struct {
+	url   *url.URL
+	admin *gorm.DB
+
+	// Set for a cluster started by startCluster.
+	dir   string
+	pgCtl string
+}
+
+// startServer returns the Postgres server for mode (see the package doc),
+// or nil to use SQLite.
+func startServer(mode string) (*postgresServer, error) {
+	switch mode {
+	case "sqlite":
+		return nil, nil
+	case "", "postgres":
+	default:
+		return nil, fmt.Errorf("E2E_DB must be postgres or sqlite, got %q", mode)
+	}
+
+	if raw := os.Getenv("E2E_DATABASE_URL"); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("E2E_DATABASE_URL: %w", err)
+		}
+		return connectServer(&postgresServer{url: u})
+	}
+
+	s, err := startCluster()
+	if err == nil {
+		return s, nil
+	}
+	if mode == "postgres" {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "e2e: using SQLite, no Postgres available: %v\n", err)
+	return nil, nil
+}
+
+func connectServer(s *postgresServer) (*postgresServer, error) {
+	admin, err := gorm.Open(postgres.Open(s.url.String()), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		s.stop()
+		return nil, err
+	}
+	s.admin = admin
+	return s, nil
+}
+
+// findBinary looks for a Postgres program in E2E_POSTGRES_BIN, e.g. the bin
+// directory of unpacked embedded-postgres binaries, then on PATH.
+func findBinary(name string) (string, error) {
+	if dir := os.Getenv("E2E_POSTGRES_BIN"); dir != "" {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return exec.LookPath(name)
+}
+
+// startCluster runs initdb and pg_ctl to start a private Postgres listening
+// only on a unix socket in a temporary directory.
+func startCluster() (*postgresServer, error) {
+	if os.Geteuid() == 0 {
+		return nil, errors.New("Postgres refuses to run as root; set E2E_DATABASE_URL instead")
+	}
+	initdb, err := findBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	pgCtl, err := findBinary("pg_ctl")
+	if err != nil {
+		return nil, err
+	}
+
+	// Socket paths are limited to about 100 bytes, so stay out of
+	// t.TempDir()'s long names.
+	dir, err := os.MkdirTemp("", "dbo-e2e-")
+	if err != nil {
+		return nil, err
+	}
+	s := &postgresServer{dir: dir}
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		s.stop()
+		return nil, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		s.stop()
+		return nil, err
+	}
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses= -c fsync=off", port, dir)
+	out, err = exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", options, "-w", "start").CombinedOutput()
+	if err != nil {
+		s.stop()
+		return nil, fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+	s.pgCtl = pgCtl
+
+	s.url = &url.URL{
+		Scheme: "postgres",
+		User:   url.User("postgres"),
+		Path:   "/postgres",
+		RawQuery: url.Values{
+			"host":    {dir},
+			"port":    {strconv.Itoa(port)},
+			"sslmode": {"disable"},
+		}.Encode(),
+	}
+	return connectServer(s)
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (s *postgresServer) createDatabase(name string, config *gorm.Config) (*gorm.DB, error) {
+	if err := s.admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		return nil, err
+	}
+	u := *s.url
+	u.Path = "/" + name
+	return gorm.Open(postgres.Open(u.String()), config)
+}
+
+func (s *postgresServer) dropDatabase(name string, db *gorm.DB) error {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	return s.admin.Exec("DROP DATABASE IF EXISTS " + name).Error
+}
+
+func (s *postgresServer) stop() {
+	if s.admin != nil {
+		if sqlDB, err := s.admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	if s.pgCtl != "" {
+		exec.Command(s.pgCtl, "-D", filepath.Join(s.dir, "data"), "-m", "immediate", "stop").Run()
+	}
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
@@ -0,0 +1,234 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/models"
+)
+
+const collectionFile = "../../DBO.postman_collection.json"
+
+type postmanCollection struct {
+	Item     []postmanItem `json:"item"`
+	Variable []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"variable"`
+}
+
+type postmanItem struct {
+	Name  string        `json:"name"`
+	Item  []postmanItem `json:"item"`
+	Event []struct {
+		Listen string `json:"listen"`
+		Script struct {
+			Exec []string `json:"exec"`
+		} `json:"script"`
+	} `json:"event"`
+	Request *struct {
+		Method string `json:"method"`
+		Header []struct {
+			Key      string `json:"key"`
+			Value    string `json:"value"`
+			Disabled bool   `json:"disabled"`
+		} `json:"header"`
+		Body struct {
+			Raw string `json:"raw"`
+		} `json:"body"`
+		URL struct {
+			Raw string `json:"raw"`
+		} `json:"url"`
+	} `json:"request"`
+}
+
+// postmanRequest is a request of the collection named "Folder/Request".
+type postmanRequest struct {
+	name string
+	item postmanItem
+}
+
+func flatten(prefix string, items []postmanItem) []postmanRequest {
+	requests := []postmanRequest{}
+	for _, item := range items {
+		name := item.Name
+		if prefix != "" {
+			name = prefix + "/" + name
+		}
+		if item.Request != nil {
+			requests = append(requests, postmanRequest{name: name, item: item})
+		}
+		requests = append(requests, flatten(name, item.Item)...)
+	}
+	return requests
+}
+
+// setVariable matches the test scripts that save part of a response, e.g.
+// pm.collectionVariables.set('access_token', jsonData.data.access_token).
+var setVariable = regexp.MustCompile(`pm\.collectionVariables\.set\(\s*['"](\w+)['"]\s*,\s*jsonData\.([\w.]+)\s*\)`)
+
+var variableRef = regexp.MustCompile(`\{\{(\w+)\}\}`)
+
+type postmanCase struct {
+	status int
+	// error is the expected error message, if any.
+	error string
+	// check, when set, looks further into a response.
+	check func(t *testing.T, h *Harness, res *Response)
+}
+
+// postmanCases are what each request of the collection must answer when the
+// collection is run in order on a new database. Register seeds the new
+// tenant with fixtures so the IDs the collection uses exist: customers 1
+// (Cust 1) and 2, and orders 1 to 16 of customer 2. Customer 1 is deleted
+// before the order requests, which then can't use it any more.
+var postmanCases = map[string]postmanCase{
+	"Auth/Register": {status: http.StatusCreated, check: func(t *testing.T, h *Harness, res *Response) {
+		seedFixtures(t, h, uint(res.Map()["tenant_id"].(float64)))
+	}},
+	"Auth/Login":         {status: http.StatusOK},
+	"Auth/Match Token":   {status: http.StatusOK},
+	"Auth/Refresh Token": {status: http.StatusOK},
+
+	"Customer/Get All Customers": {status: http.StatusOK, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.count", float64(2))
+	}},
+	"Customer/Get Customer Detail": {status: http.StatusOK, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.name", "Cust 1")
+	}},
+	"Customer/Insert Customer": {status: http.StatusCreated, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.email", "cust2@yopmail.com")
+	}},
+	"Customer/Update Customer": {status: http.StatusOK, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.gender", "male")
+	}},
+	"Customer/Delete Customer": {status: http.StatusOK},
+
+	"Order/Get All Orders": {status: http.StatusOK, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.count", float64(16))
+	}},
+	"Order/Get Order Detail": {status: http.StatusOK, check: func(t *testing.T, h *Harness, res *Response) {
+		expectValue(t, res, "data.id", float64(7))
+	}},
+	"Order/Insert Order": {status: http.StatusBadRequest, error: "Customer not found"},
+	"Order/Update Order": {status: http.StatusBadRequest, error: "Customer not found"},
+	"Order/Delete Order": {status: http.StatusOK},
+}
+
+func seedFixtures(t *testing.T, h *Harness, tenantID uint) {
+	t.Helper()
+
+	customers := []models.Customer{
+		{TenantID: tenantID, Name: "Cust 1", Email: "cust1@yopmail.com", PhoneNumber: "087786552751", Gender: "male"},
+		{TenantID: tenantID, Name: "Cust 3", Email: "cust3@yopmail.com", PhoneNumber: "087786552753", Gender: "female"},
+	}
+	if err := h.DB.Create(&customers).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 16; i++ {
+		order := models.Order{
+			TenantID:      tenantID,
+			CustomerID:    customers[1].ID,
+			ProductName:   fmt.Sprintf("Product %d", i),
+			Quantity:      1,
+			TotalPrice:    10000,
+			PaymentStatus: "unpaid",
+		}
+		if err := h.DB.Create(&order).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func expectValue(t *testing.T, res *Response, path string, want interface{}) {
+	t.Helper()
+
+	if got := res.Lookup(path); got != want {
+		t.Errorf("%s is %v, want %v", path, got, want)
+	}
+}
+
+// TestPostmanCollection runs every request of DBO.postman_collection.json in
+// order, carrying variables between them the way the collection's test
+// scripts do. Requests without an Authorization header are sent with
+// {{access_token}}, as the collection is meant to be used.
+func TestPostmanCollection(t *testing.T) {
+	raw, err := os.ReadFile(collectionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(raw, &collection); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHarness(t)
+	variables := map[string]string{}
+	for _, variable := range collection.Variable {
+		variables[variable.Key] = variable.Value
+	}
+	variables["url"] = h.Server.URL
+	expand := func(s string) string {
+		return variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+			return variables[variableRef.FindStringSubmatch(ref)[1]]
+		})
+	}
+
+	requests := flatten("", collection.Item)
+	seen := map[string]bool{}
+	for _, request := range requests {
+		seen[request.name] = true
+		want, ok := postmanCases[request.name]
+		if !ok {
+			t.Errorf("%s: no expectation in postmanCases", request.name)
+			continue
+		}
+
+		item := request.item
+		req, err := http.NewRequest(item.Request.Method, expand(item.Request.URL.Raw), strings.NewReader(expand(item.Request.Body.Raw)))
+		if err != nil {
+			t.Fatalf("%s: %v", request.name, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for _, header := range item.Request.Header {
+			if !header.Disabled {
+				req.Header.Set(header.Key, expand(header.Value))
+			}
+		}
+		if req.Header.Get("Authorization") == "" && variables["access_token"] != "" {
+			req.Header.Set("Authorization", "Bearer "+variables["access_token"])
+		}
+
+		res := h.Do(req)
+		if res.Status != want.status {
+			t.Fatalf("%s: got status %d, want %d: %s", request.name, res.Status, want.status, res.Body)
+		}
+		if got := res.ErrorMessage(); got != want.error {
+			t.Errorf("%s: got error %q, want %q", request.name, got, want.error)
+		}
+		if want.check != nil {
+			want.check(t, h, res)
+		}
+
+		for _, event := range item.Event {
+			if event.Listen != "test" {
+				continue
+			}
+			for _, match := range setVariable.FindAllStringSubmatch(strings.Join(event.Script.Exec, "\n"), -1) {
+				value, _ := res.Lookup(match[2]).(string)
+				variables[match[1]] = value
+			}
+		}
+	}
+
+	for name := range postmanCases {
+		if !seen[name] {
+			t.Errorf("%s: in postmanCases but not in the collection", name)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/controllers"
+)
+
+// TestForwardedForNeedsATrustedProxy uses an API key with a spoofed
+// X-Forwarded-For. The key's last used IP only takes the header once the
+// proxy it came through is trusted.
+func TestForwardedForNeedsATrustedProxy(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+	key := h.Call(http.MethodPost, "/api/api-keys", alice, map[string]interface{}{
+		"name":   "Reports",
+		"scopes": []string{"customers:read"},
+	}).Expect(t, http.StatusCreated).Map()["key"].(string)
+
+	useKey := func() {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, h.Server.URL+"/api/customers", nil)
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		h.Do(req).Expect(t, http.StatusOK)
+	}
+	lastUsedIP := func() interface{} {
+		t.Helper()
+		keys := h.Call(http.MethodGet, "/api/api-keys", alice, nil).Expect(t, http.StatusOK).Lookup("data").([]interface{})
+		return keys[0].(map[string]interface{})["last_used_ip"]
+	}
+
+	useKey()
+	if ip := lastUsedIP(); ip != "127.0.0.1" {
+		t.Fatalf("last_used_ip is %v with no trusted proxy, want 127.0.0.1", ip)
+	}
+
+	controllers.Config.HTTP.TrustedProxies = "127.0.0.1, 10.0.0.0/8"
+	h.Reload()
+	useKey()
+	if ip := lastUsedIP(); ip != "203.0.113.7" {
+		t.Fatalf("last_used_ip is %v behind a trusted proxy, want 203.0.113.7", ip)
+	}
+}
@@ -0,0 +1,40 @@
+package e2e
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/routers"
+)
+
+// TestEveryRoute calls every route of the router as a tenant admin with an
+// empty JSON body and unknown IDs. Whatever a route makes of that, it must
+// answer with JSON, except the Prometheus metrics, and not fail with a 500.
+func TestEveryRoute(t *testing.T) {
+	h := NewHarness(t)
+	h.Register("admin@example.com")
+
+	for _, route := range routers.SetupRouter(h.DB).Routes() {
+		name := route.Method + " " + route.Path
+		t.Run(name, func(t *testing.T) {
+			h := h.WithT(t)
+			path := route.Path
+			for _, param := range []string{":id", ":session_id", ":role"} {
+				path = strings.ReplaceAll(path, param, "999999")
+			}
+
+			// Some routes end the session, so each gets a token of its own.
+			token := h.Login("admin@example.com").AccessToken
+			res := h.Call(route.Method, path, token, "{}")
+
+			if res.Status >= http.StatusInternalServerError {
+				t.Fatalf("got status %d: %s", res.Status, res.Body)
+			}
+			if path != controllers.Config.Metrics.Path && !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") {
+				t.Errorf("got status %d without JSON: %s", res.Status, res.Body)
+			}
+		})
+	}
+}
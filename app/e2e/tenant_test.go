@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestTenantsDontSeeEachOthersData(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+	bob := h.SignUp("bob@example.com")
+
+	customer := h.Call(http.MethodPost, "/api/customers", alice, map[string]string{
+		"name":         "Cust 1",
+		"email":        "cust1@yopmail.com",
+		"phone_number": "087786552751",
+		"gender":       "female",
+	}).Expect(t, http.StatusCreated).Map()
+	customerPath := fmt.Sprintf("/api/customers/%v", customer["id"])
+
+	h.Call(http.MethodGet, customerPath, alice, nil).Expect(t, http.StatusOK)
+	res := h.Call(http.MethodGet, "/api/customers?search=CUST%201", alice, nil).Expect(t, http.StatusOK)
+	expectValue(t, res, "data.count", float64(1))
+
+	h.Call(http.MethodGet, customerPath, bob, nil).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodDelete, customerPath, bob, nil).Expect(t, http.StatusNotFound)
+
+	res = h.Call(http.MethodGet, "/api/customers", bob, nil).Expect(t, http.StatusOK)
+	expectValue(t, res, "data.count", float64(0))
+
+	// Emails are only unique within a tenant.
+	h.Call(http.MethodPost, "/api/customers", bob, map[string]string{
+		"name":         "Cust 1",
+		"email":        "cust1@yopmail.com",
+		"phone_number": "087786552751",
+		"gender":       "female",
+	}).Expect(t, http.StatusCreated)
+}
+
+// TestIDParamsStayInTenant sends SQL in place of route IDs. Whatever it
+// says, it must not reach rows of another tenant.
+func TestIDParamsStayInTenant(t *testing.T) {
+	h := NewHarness(t)
+	aliceID := h.Register("alice@example.com")["user_id"]
+	alice := h.Login("alice@example.com").AccessToken
+	bobID := h.Register("bob@example.com")["user_id"]
+	injected := url.PathEscape(fmt.Sprintf("0) OR (id=%v", bobID))
+
+	h.Call(http.MethodGet, "/api/users/"+injected, alice, nil).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodPut, "/api/users/"+injected+"/email", alice, map[string]string{
+		"email": "taken-over@example.com",
+	}).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodPost, "/api/users/"+injected+"/roles", alice, map[string]string{
+		"role": "admin",
+	}).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodDelete, "/api/users/"+injected+"/roles/viewer", alice, nil).Expect(t, http.StatusNotFound)
+
+	bobTokens := h.Login("bob@example.com")
+	bob := bobTokens.AccessToken
+	key := h.Call(http.MethodPost, "/api/api-keys", bob, map[string]interface{}{
+		"name":   "Reports",
+		"scopes": []string{"customers:read"},
+	}).Expect(t, http.StatusCreated).Map()
+	keyID := key["api_key"].(map[string]interface{})["id"]
+	injected = url.PathEscape(fmt.Sprintf("0) OR (id=%v", keyID))
+
+	h.Call(http.MethodPut, "/api/api-keys/"+injected, alice, map[string]string{
+		"name": "Mine now",
+	}).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodDelete, "/api/api-keys/"+injected, alice, nil).Expect(t, http.StatusNotFound)
+
+	client := h.Call(http.MethodPost, "/api/oauth/clients", bob, map[string]interface{}{
+		"name":          "Reports",
+		"redirect_uris": []string{"https://reports.example.com/callback"},
+		"scopes":        []string{"customers:read"},
+	}).Expect(t, http.StatusCreated).Map()
+	clientID := client["client"].(map[string]interface{})["id"]
+	injected = url.PathEscape(fmt.Sprintf("0) OR (id=%v", clientID))
+
+	h.Call(http.MethodDelete, "/api/oauth/clients/"+injected, alice, nil).Expect(t, http.StatusNotFound)
+
+	sessions := h.Call(http.MethodGet, "/api/auth/sessions", bob, nil).Expect(t, http.StatusOK).Lookup("data").([]interface{})
+	sessionID := sessions[0].(map[string]interface{})["id"]
+	injected = url.PathEscape(fmt.Sprintf("0) OR (id=%v", sessionID))
+
+	h.Call(http.MethodDelete, "/api/auth/sessions/"+injected, alice, nil).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodDelete, fmt.Sprintf("/api/users/%v/sessions/%s", aliceID, injected), alice, nil).Expect(t, http.StatusNotFound)
+	h.Call(http.MethodPost, "/api/auth/refresh-token", "", map[string]string{
+		"refresh_token": bobTokens.RefreshToken,
+	}).Expect(t, http.StatusOK)
+}
@@ -0,0 +1,54 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/golang-jwt/jwt"
+)
+
+// TestTokensNameTheUserByID checks that tokens name their user by ID, and
+// that a token naming an email, which can pass to another account, is
+// refused.
+func TestTokensNameTheUserByID(t *testing.T) {
+	h := NewHarness(t)
+	userID := h.Register("alice@example.com")["user_id"]
+	accessToken := h.Login("alice@example.com").AccessToken
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(accessToken, ".")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != fmt.Sprint(userID) {
+		t.Errorf("sub is %v, want the user ID %v", claims["sub"], userID)
+	}
+
+	now := time.Now()
+	emailToken, err := controllers.SigningKeys.Sign(controllers.TokenClaims{
+		TokenType: controllers.TokenTypeAccess,
+		StandardClaims: jwt.StandardClaims{
+			Id:        "e2e-email-subject",
+			Subject:   "alice@example.com",
+			Issuer:    controllers.Config.JWT.Issuer,
+			Audience:  controllers.Config.JWT.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(time.Minute).Unix(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := h.Call(http.MethodGet, "/api/me", emailToken, nil).Expect(t, http.StatusUnauthorized)
+	expectValue(t, res, "code", "token_unknown_user")
+}
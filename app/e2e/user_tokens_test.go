@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestMailedTokensFollowTheEmail changes the email of a user holding a
+// verification and a password reset token. Both were mailed to the old
+// address, so they are used up; only the verification mailed to the new
+// address still works.
+func TestMailedTokensFollowTheEmail(t *testing.T) {
+	h := NewHarness(t)
+	alice := h.SignUp("alice@example.com")
+
+	h.Call(http.MethodPost, "/api/auth/password-reset/request", "", map[string]string{
+		"email": "alice@example.com",
+	}).Expect(t, http.StatusOK)
+	resetToken := h.MailedToken("alice@example.com", "Reset token: ")
+	oldVerificationToken := h.MailedToken("alice@example.com", "Verification token: ")
+
+	h.Call(http.MethodPatch, "/api/me", alice, map[string]string{
+		"email":            "alice@example.org",
+		"current_password": testPassword,
+	}).Expect(t, http.StatusOK)
+
+	res := h.Call(http.MethodPost, "/api/auth/password-reset/confirm", "", map[string]string{
+		"token":    resetToken,
+		"password": "correct-staple-77",
+	}).Expect(t, http.StatusUnauthorized)
+	expectValue(t, res, "code", "token_used")
+
+	res = h.Call(http.MethodPost, "/api/auth/verify-email/confirm", "", map[string]string{
+		"token": oldVerificationToken,
+	}).Expect(t, http.StatusUnauthorized)
+	expectValue(t, res, "code", "token_used")
+
+	h.Call(http.MethodPost, "/api/auth/verify-email/confirm", "", map[string]string{
+		"token": h.MailedToken("alice@example.org", "Verification token: "),
+	}).Expect(t, http.StatusOK)
+}
+
+// TestMailedTokensFollowAnEmailChangedByAnAdmin is
+// TestMailedTokensFollowTheEmail with the email changed by an admin of the
+// tenant.
+func TestMailedTokensFollowAnEmailChangedByAnAdmin(t *testing.T) {
+	h := NewHarness(t)
+	userID := h.Register("alice@example.com")["user_id"]
+	alice := h.Login("alice@example.com").AccessToken
+
+	h.Call(http.MethodPost, "/api/auth/password-reset/request", "", map[string]string{
+		"email": "alice@example.com",
+	}).Expect(t, http.StatusOK)
+	resetToken := h.MailedToken("alice@example.com", "Reset token: ")
+
+	h.Call(http.MethodPut, fmt.Sprintf("/api/users/%v/email", userID), alice, map[string]string{
+		"email": "alice@example.org",
+	}).Expect(t, http.StatusOK)
+
+	res := h.Call(http.MethodPost, "/api/auth/password-reset/confirm", "", map[string]string{
+		"token":    resetToken,
+		"password": "correct-staple-77",
+	}).Expect(t, http.StatusUnauthorized)
+	expectValue(t, res, "code", "token_used")
+}
@@ -0,0 +1,34 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// TestAdminsCantLockThemselvesOut has an admin disable, delete and force a
+// password reset on their own account. Each is refused and the admin's token
+// keeps working.
+func TestAdminsCantLockThemselvesOut(t *testing.T) {
+	h := NewHarness(t)
+	userID := h.Register("admin@example.com")["user_id"]
+	token := h.Login("admin@example.com").AccessToken
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, fmt.Sprintf("/api/users/%v/disable", userID)},
+		{http.MethodDelete, fmt.Sprintf("/api/users/%v", userID)},
+		{http.MethodPost, fmt.Sprintf("/api/users/%v/force-password-reset", userID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res := h.Call(tt.method, tt.path, token, nil).Expect(t, http.StatusBadRequest)
+			if msg := res.ErrorMessage(); msg != "You can't do this to your own account" {
+				t.Errorf("got error %q", msg)
+			}
+			h.Call(http.MethodGet, "/api/me", token, nil).Expect(t, http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Key is one signing key. Retired keys may have no private half; they are
+// only kept to verify tokens issued before a rotation.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+}
+
+// Manager signs tokens with its current key and verifies tokens against every
+// key it holds, selected by the kid header.
+type Manager struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// NewHMACManager returns a Manager with a single HS256 key. It is the fallback
+// used when no PEM keys are configured; it publishes nothing in the JWKS.
+func NewHMACManager(secret []byte) *Manager {
+	key := &Key{
+		ID:         "hs256",
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: secret,
+		PublicKey:  secret,
+	}
+	return &Manager{current: key, keys: map[string]*Key{key.ID: key}}
+}
+
+// LoadDir loads every *.pem file in dir. The file name without extension is
+// the key's kid, so "2023-06.pem" is published as kid "2023-06". Files may hold
+// an RSA or Ed25519 private key (PKCS#1 or PKCS#8) or, for retired keys, just
+// the public key (PKIX). currentKid selects the key new tokens are signed with.
+func LoadDir(dir string, currentKid string) (*Manager, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	m := &Manager{keys: map[string]*Key{}}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadKey(path, kid)
+		if err != nil {
+			return nil, fmt.Errorf("loading signing key %s: %w", path, err)
+		}
+		m.keys[kid] = key
+	}
+
+	current, ok := m.keys[currentKid]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", currentKid, dir)
+	}
+	if current.PrivateKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", currentKid)
+	}
+	m.current = current
+
+	return m, nil
+}
+
+func loadKey(path string, kid string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	return key, nil
+}
+
+// Sign signs claims with the current key and stamps its kid header.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.current.Method, claims)
+	token.Header["kid"] = m.current.ID
+	return token.SignedString(m.current.PrivateKey)
+}
+
+// Keyfunc resolves the verification key for a parsed token from its kid
+// header. It is meant to be passed to jwt.Parse.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.PublicKey, nil
+}
+
+// publicKeys returns the asymmetric keys, sorted by kid. HMAC secrets must
+// never be published.
+func (m *Manager) publicKeys() []*Key {
+	var published []*Key
+	for _, key := range m.keys {
+		if _, ok := key.Method.(*jwt.SigningMethodHMAC); !ok {
+			published = append(published, key)
+		}
+	}
+	sort.Slice(published, func(i, j int) bool {
+		return published[i].ID < published[j].ID
+	})
+	return published
+}
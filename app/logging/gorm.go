@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQuery is how long a query may take before it is logged as a warning.
+const slowQuery = 200 * time.Millisecond
+
+// GormLogger sends GORM's logs to slog. Every query is logged at debug level,
+// slow ones as warnings and failed ones as errors, with the request ID of the
+// context the query ran with. Queries are logged with their placeholders,
+// never with the values bound to them.
+type GormLogger struct {
+	Logger *slog.Logger
+	Level  gormlogger.LogLevel
+}
+
+// NewGormLogger returns a GormLogger writing to the default logger.
+func NewGormLogger() *GormLogger {
+	return &GormLogger{Level: gormlogger.Info}
+}
+
+func (l *GormLogger) logger() *slog.Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return slog.Default()
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.Level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		l.logger().InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		l.logger().WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		l.logger().ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	level := slog.LevelDebug
+	msg := "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.Level >= gormlogger.Error:
+		level = slog.LevelError
+		msg = "query failed"
+	case elapsed > slowQuery && l.Level >= gormlogger.Warn:
+		level = slog.LevelWarn
+		msg = "slow query"
+	}
+
+	logger := l.logger()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	sql, rows := fc()
+	attrs := []any{slog.String("sql", sql), slog.Duration("duration", elapsed), slog.Int64("rows", rows)}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.Log(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the values bound to a query, so passwords and tokens in
+// them never reach the log.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
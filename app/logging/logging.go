@@ -0,0 +1,104 @@
+// Package logging sets up the structured logger the whole app writes to.
+// Records logged with a request's context carry its request ID, and
+// passwords, tokens and DSNs are redacted before anything is written.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fajaaro/dbo/app/config"
+	"github.com/gin-gonic/gin"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the ID of the request it belongs to.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New returns a logger that writes cfg.Format records of cfg.Level and above
+// to w.
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level:       ParseLevel(cfg.Level),
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(w, options)
+	} else {
+		handler = slog.NewTextHandler(w, options)
+	}
+	return slog.New(requestHandler{handler})
+}
+
+// Setup makes New(cfg, os.Stderr) the default logger. Lines written with the
+// log package go through it as well, at info level, and gin's own output,
+// e.g. the routes it lists in debug mode, at debug and error level.
+func Setup(cfg config.LogConfig) {
+	slog.SetDefault(New(cfg, os.Stderr))
+	gin.DefaultWriter = lineWriter{slog.LevelDebug}
+	gin.DefaultErrorWriter = lineWriter{slog.LevelError}
+}
+
+// lineWriter logs each line written to it as a record of its level.
+type lineWriter struct {
+	level slog.Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "[GIN-debug]"))
+		if line != "" {
+			slog.Log(context.Background(), w.level, line)
+		}
+	}
+	return len(p), nil
+}
+
+// ParseLevel converts a LOG_LEVEL value to a slog level. Unknown values,
+// which the config validation rejects, mean info.
+func ParseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// requestHandler adds the request ID of the context to every record.
+type requestHandler struct {
+	slog.Handler
+}
+
+func (h requestHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestHandler) WithGroup(name string) slog.Handler {
+	return requestHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fajaaro/dbo/app/config"
+	"gorm.io/gorm"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the Mailer for cfg.Driver: "smtp" for real delivery, or
+// "file" (the default) and "db" to keep mail in a local outbox during
+// development and tests.
+func New(cfg config.MailConfig, db *gorm.DB) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "file":
+		return NewFileMailer(cfg.OutboxDir, cfg.From), nil
+	case "db":
+		return NewOutboxMailer(db, cfg.From), nil
+	case "smtp":
+		return NewSMTPMailer(
+			cfg.SMTPHost,
+			strconv.Itoa(cfg.SMTPPort),
+			cfg.SMTPUsername,
+			cfg.SMTPPassword,
+			cfg.From,
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message) []byte {
+	return []byte("From: " + from + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		msg.Body + "\r\n")
+}
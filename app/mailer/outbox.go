@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"github.com/fajaaro/dbo/app/models"
+	"gorm.io/gorm"
+)
+
+// OutboxMailer stores every message in the outbox_messages table instead of
+// delivering it.
+type OutboxMailer struct {
+	DB   *gorm.DB
+	From string
+}
+
+func NewOutboxMailer(db *gorm.DB, from string) *OutboxMailer {
+	return &OutboxMailer{DB: db, From: from}
+}
+
+func (m *OutboxMailer) Send(msg Message) error {
+	return m.DB.Create(&models.OutboxMessage{
+		From:    m.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Body:    msg.Body,
+	}).Error
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startedKey = "metrics:started"
+
+var (
+	mu sync.Mutex
+	// dbCollectors are the collectors of the instrumented database, swapped
+	// out when another one is instrumented.
+	dbCollectors []prometheus.Collector
+)
+
+// InstrumentDB times every query run through db and exports the connection
+// pool statistics of db, as go_sql_* metrics labeled with db_name name. With
+// business set it also exports customer and order counts, queried on every
+// scrape. The app has one database; instrumenting another one replaces the
+// pool and business metrics of the previous one.
+func InstrumentDB(db *gorm.DB, name string, business bool) error {
+	if err := db.Use(queryTimer{}); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	collected := []prometheus.Collector{collectors.NewDBStatsCollector(sqlDB, name)}
+	if business {
+		collected = append(collected, businessCollector{db})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, collector := range dbCollectors {
+		Registry.Unregister(collector)
+	}
+	dbCollectors = nil
+	for _, collector := range collected {
+		if err := Registry.Register(collector); err != nil {
+			return err
+		}
+		dbCollectors = append(dbCollectors, collector)
+	}
+	return nil
+}
+
+// queryTimer is a GORM plugin that feeds DBQueryDuration and DBQueryErrors.
+type queryTimer struct{}
+
+func (queryTimer) Name() string {
+	return "metrics"
+}
+
+func (queryTimer) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("metrics:before_create", startTimer),
+		callbacks.Create().After("*").Register("metrics:after_create", stopTimer("create")),
+		callbacks.Query().Before("*").Register("metrics:before_query", startTimer),
+		callbacks.Query().After("*").Register("metrics:after_query", stopTimer("query")),
+		callbacks.Update().Before("*").Register("metrics:before_update", startTimer),
+		callbacks.Update().After("*").Register("metrics:after_update", stopTimer("update")),
+		callbacks.Delete().Before("*").Register("metrics:before_delete", startTimer),
+		callbacks.Delete().After("*").Register("metrics:after_delete", stopTimer("delete")),
+		callbacks.Row().Before("*").Register("metrics:before_row", startTimer),
+		callbacks.Row().After("*").Register("metrics:after_row", stopTimer("row")),
+		callbacks.Raw().Before("*").Register("metrics:before_raw", startTimer),
+		callbacks.Raw().After("*").Register("metrics:after_raw", stopTimer("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startedKey, time.Now())
+}
+
+func stopTimer(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if started, ok := db.InstanceGet(startedKey); ok {
+			DBQueryDuration.WithLabelValues(operation).Observe(time.Since(started.(time.Time)).Seconds())
+		}
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
+
+var (
+	ordersDesc = prometheus.NewDesc("dbo_orders",
+		"Orders of every tenant by payment status.", []string{"payment_status"}, nil)
+	customersDesc = prometheus.NewDesc("dbo_customers",
+		"Customers of every tenant.", nil, nil)
+)
+
+// businessScrapeTimeout bounds the queries of one scrape, so a slow database
+// can't pile up scrapes.
+const businessScrapeTimeout = 5 * time.Second
+
+// businessCollector counts customers and orders when scraped.
+type businessCollector struct {
+	db *gorm.DB
+}
+
+func (c businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ordersDesc
+	ch <- customersDesc
+}
+
+func (c businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessScrapeTimeout)
+	defer cancel()
+	db := c.db.WithContext(ctx)
+
+	var orders []struct {
+		PaymentStatus string
+		Count         int64
+	}
+	err := db.Model(&models.Order{}).Select("COALESCE(payment_status, '') AS payment_status, COUNT(*) AS count").Group("payment_status").Scan(&orders).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(ordersDesc, err)
+	}
+	for _, row := range orders {
+		ch <- prometheus.MustNewConstMetric(ordersDesc, prometheus.GaugeValue, float64(row.Count), row.PaymentStatus)
+	}
+
+	var customers int64
+	if err := db.Model(&models.Customer{}).Count(&customers).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(customersDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(customersDesc, prometheus.GaugeValue, float64(customers))
+}
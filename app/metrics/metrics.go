@@ -0,0 +1,82 @@
+// Package metrics holds the Prometheus metrics of the app and the handler
+// that serves them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric the handler serves.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dbo_http_requests_total",
+		Help: "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dbo_http_request_duration_seconds",
+		Help:    "Time to answer HTTP requests by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LoginSuccesses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dbo_login_successes_total",
+		Help: "Logins that issued tokens, with a password or a second factor.",
+	})
+
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dbo_login_failures_total",
+		Help: "Refused logins by reason.",
+	}, []string{"reason"})
+
+	TokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dbo_token_refreshes_total",
+		Help: "Refresh token exchanges by result: success or the error code.",
+	}, []string{"result"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dbo_db_query_duration_seconds",
+		Help:    "Time taken by database queries by operation.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dbo_db_query_errors_total",
+		Help: "Failed database queries by operation. Finding no row isn't counted.",
+	}, []string{"operation"})
+)
+
+// Reasons a login is refused, the values of LoginFailures' reason label.
+const (
+	LoginInvalidCredentials    = "invalid_credentials"
+	LoginThrottled             = "throttled"
+	LoginDisabled              = "disabled"
+	LoginPasswordResetRequired = "password_reset_required"
+	LoginEmailNotVerified      = "email_not_verified"
+	LoginInvalidMFACode        = "invalid_mfa_code"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		LoginSuccesses,
+		LoginFailures,
+		TokenRefreshes,
+		DBQueryDuration,
+		DBQueryErrors,
+	)
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
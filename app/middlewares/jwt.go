@@ -1,20 +1,31 @@
 package middlewares
 
 import (
+	"log/slog"
 	"net/http"
 	"strings"
 
-	"github.com/fajaaro/dbo/app"
 	"github.com/fajaaro/dbo/app/controllers"
 	"github.com/fajaaro/dbo/app/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func JWT() gin.HandlerFunc {
+// JWT authenticates the request with a bearer access token or, for machine
+// clients, with an API key sent as "Authorization: ApiKey <key>" or in the
+// X-API-Key header. API key requests get "api_key" set instead of "claims".
+func JWT(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		res := models.JsonResponse{Success: true}
+		db := db.WithContext(c.Request.Context())
 
-		if len(strings.Split(c.Request.Header.Get("Authorization"), " ")) != 2 {
+		authorization := strings.Split(c.Request.Header.Get("Authorization"), " ")
+		apiKey := c.Request.Header.Get("X-API-Key")
+		if len(authorization) == 2 && authorization[0] == "ApiKey" {
+			apiKey = authorization[1]
+		}
+
+		if apiKey == "" && len(authorization) != 2 {
 			errorMsg := "invalid token"
 			res.Success = false
 			res.Error = &errorMsg
@@ -23,20 +34,125 @@ func JWT() gin.HandlerFunc {
 			return
 		}
 
-		accessToken := strings.Split(c.Request.Header.Get("Authorization"), " ")[1]
-
-		user, err := controllers.ValidateAccessToken(accessToken, app.GetDb())
+		var (
+			user *models.User
+			err  error
+		)
+		if apiKey != "" {
+			var key *models.APIKey
+			user, key, err = controllers.ValidateAPIKey(apiKey, c.ClientIP(), db)
+			if err == nil {
+				c.Set("api_key", key)
+			}
+		} else {
+			var claims *controllers.TokenClaims
+			user, claims, err = controllers.ValidateAccessToken(authorization[1], db)
+			if err == nil {
+				c.Set("claims", claims)
+			}
+		}
 		if err != nil {
 			errorMsg := err.Error()
 			res.Success = false
 			res.Error = &errorMsg
 
-			c.JSON(http.StatusUnauthorized, res)
+			status := http.StatusInternalServerError
+			if tokenErr, ok := err.(*controllers.TokenError); ok {
+				res.Code = &tokenErr.Code
+				status = http.StatusUnauthorized
+			}
+			c.JSON(status, res)
 			c.Abort()
 			return
 		}
 
 		c.Set("user", user)
+		c.Set("tenant_id", user.TenantID)
+
+		claims, ok := c.Get("claims")
+		if !ok || claims.(*controllers.TokenClaims).Actor == nil {
+			c.Next()
+			return
+		}
+
+		// Label impersonated requests in the log and the audit trail.
+		actor := claims.(*controllers.TokenClaims).Actor.Email
+		c.Set("actor", actor)
+		slog.InfoContext(c.Request.Context(), "impersonated request",
+			slog.String("actor", actor),
+			slog.String("user", user.Email),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+		)
+
+		c.Next()
+
+		controllers.RecordImpersonatedRequest(db, c)
+	}
+}
+
+// RejectImpersonation refuses requests made with an admin's impersonation
+// token. It guards role and user management, so impersonating another admin
+// can't be used to act on the tenant's accounts under their name.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if abortImpersonated(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortImpersonated answers 403 and reports true when the request was made
+// with an impersonation token.
+func abortImpersonated(c *gin.Context) bool {
+	claims, ok := c.Get("claims")
+	if !ok || claims.(*controllers.TokenClaims).Actor == nil {
+		return false
+	}
+
+	errorMsg := "Impersonation tokens can't be used for this endpoint"
+	errorCode := "impersonation_not_allowed"
+	c.JSON(http.StatusForbidden, models.JsonResponse{
+		Success: false,
+		Error:   &errorMsg,
+		Code:    &errorCode,
+	})
+	c.Abort()
+	return true
+}
+
+// RequireAccessToken only lets through requests made with the user's own
+// access token, not with an API key, a token issued to an OAuth client or an
+// admin's impersonation token. It guards endpoints that manage the user's
+// sessions and credentials.
+func RequireAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := models.JsonResponse{Success: true}
+
+		if _, ok := c.Get("api_key"); ok {
+			errorMsg := "API keys can't be used for this endpoint"
+			errorCode := "api_key_not_allowed"
+			res.Success = false
+			res.Error = &errorMsg
+			res.Code = &errorCode
+			c.JSON(http.StatusForbidden, res)
+			c.Abort()
+			return
+		}
+		if abortImpersonated(c) {
+			return
+		}
+		if claims, ok := c.Get("claims"); ok && claims.(*controllers.TokenClaims).ClientID != "" {
+			errorMsg := "OAuth access tokens can't be used for this endpoint"
+			errorCode := "oauth_token_not_allowed"
+			res.Success = false
+			res.Error = &errorMsg
+			res.Code = &errorCode
+			c.JSON(http.StatusForbidden, res)
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fajaaro/dbo/app/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// knownMethods are the methods counted by name; anything else is "other".
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// Metrics counts and times requests by method, route template and status, so
+// /api/orders/1 and /api/orders/2 are one series. Requests that match no
+// route are counted as "unmatched" rather than by path, and unknown methods
+// as "other", so clients can't create series at will.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "other"
+		}
+		labels := []string{method, route, strconv.Itoa(c.Writer.Status())}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
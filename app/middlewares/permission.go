@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission only lets the request through when one of the roles of
+// the user set by JWT() grants permission, e.g. "orders:delete". Requests made
+// with an API key or an OAuth access token also need the permission among the
+// key's or token's scopes.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := models.JsonResponse{Success: true}
+
+		user := c.MustGet("user").(*models.User)
+		allowed := user.HasPermission(permission)
+		if apiKey, ok := c.Get("api_key"); ok {
+			allowed = allowed && apiKey.(*models.APIKey).HasScope(permission)
+		}
+		if claims, ok := c.Get("claims"); ok && claims.(*controllers.TokenClaims).ClientID != "" {
+			allowed = allowed && claims.(*controllers.TokenClaims).HasScope(permission)
+		}
+		if !allowed {
+			errorMsg := "Permission denied"
+			res.Success = false
+			res.Error = &errorMsg
+			c.JSON(http.StatusForbidden, res)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
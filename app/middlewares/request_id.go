@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/logging"
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs taken from clients to something safe to log
+// and echo back.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestID gives every request an ID: the X-Request-ID sent by the client
+// or proxy when there is a valid one, otherwise a new one. The ID is echoed
+// in the X-Request-ID response header, added to JSON error responses as
+// "request_id", set as "request_id" on the gin context and carried by the
+// request's context, so log lines and queries that use it are tagged with
+// it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		writer := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.flush(id)
+	}
+}
+
+// errorBodyWriter holds back JSON error bodies so the request ID can be
+// added to them once the handler is done. Everything else passes through.
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+	held bool
+}
+
+func (w *errorBodyWriter) holds() bool {
+	return w.Status() >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if w.holds() {
+		w.held = true
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *errorBodyWriter) flush(id string) {
+	if !w.held {
+		return
+	}
+
+	body := bytes.TrimSpace(w.body.Bytes())
+	if len(body) < 2 || body[0] != '{' || body[len(body)-1] != '}' {
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	var out bytes.Buffer
+	out.Write(body[:len(body)-1])
+	if len(bytes.TrimSpace(body[1:len(body)-1])) > 0 {
+		out.WriteByte(',')
+	}
+	field, _ := json.Marshal(id)
+	out.WriteString(`"request_id":`)
+	out.Write(field)
+	out.WriteByte('}')
+	w.ResponseWriter.Write(out.Bytes())
+}
+
+// Logger logs one line per request once it is done: errors for 5xx
+// responses, info for the rest. The query string is left out because it can
+// hold tokens. It goes before RequestID, so the line has the request ID and
+// the size of the body RequestID held back.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("size", c.Writer.Size()),
+			slog.String("ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery answers a panicking request with a 500 and logs the panic and
+// stack at error level.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "panic recovered",
+			slog.Any("panic", recovered),
+			slog.String("stack", string(debug.Stack())),
+		)
+
+		errorMsg := "Internal server error"
+		c.AbortWithStatusJSON(http.StatusInternalServerError, models.JsonResponse{
+			Success: false,
+			Error:   &errorMsg,
+		})
+	})
+}
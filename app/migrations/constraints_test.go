@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var checkConstraint = regexp.MustCompile(`CONSTRAINT\s+(\w+)\s+CHECK\s*\(([^()]*)\)`)
+
+// checkConstraints returns the CHECK constraints the up migrations of
+// dialect create, by name.
+func checkConstraints(t *testing.T, dialect string) map[string]string {
+	t.Helper()
+
+	sqlFiles, err := fs.Sub(files, path.Join("sql", dialect))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := Load(sqlFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraints := map[string]string{}
+	for _, migration := range migrations {
+		for _, match := range checkConstraint.FindAllStringSubmatch(migration.Up, -1) {
+			constraints[match[1]] = match[2]
+		}
+	}
+	return constraints
+}
+
+// TestCheckConstraintsMatch keeps the CHECK constraints, which are written
+// out in each dialect's migrations, the same on every backend.
+func TestCheckConstraintsMatch(t *testing.T) {
+	want := checkConstraints(t, "postgres")
+	if len(want) == 0 {
+		t.Fatal("found no CHECK constraints in the postgres migrations")
+	}
+	for _, dialect := range []string{"mysql", "sqlite"} {
+		t.Run(dialect, func(t *testing.T) {
+			if got := checkConstraints(t, dialect); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v as on postgres", got, want)
+			}
+		})
+	}
+}
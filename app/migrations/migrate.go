@@ -1,14 +1,18 @@
 package migrations
 
 import (
-	"github.com/fajaaro/dbo/app/models"
 	"gorm.io/gorm"
 )
 
-func AutoMigrate(db *gorm.DB) error {
-	err := db.AutoMigrate(&models.User{}, &models.Customer{}, &models.Order{})
+// Migrate applies pending SQL migrations and seeds the built-in roles. The
+// lock in Migrator makes it safe for several replicas to call at once.
+func Migrate(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
 	if err != nil {
 		return err
 	}
-	return nil
+	if _, err := migrator.Up(); err != nil {
+		return err
+	}
+	return SeedRoles(db)
 }
@@ -0,0 +1,358 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/dialect"
+	"gorm.io/gorm"
+)
+
+// lockKey is the lock held while migrating, so replicas starting at the
+// same time apply each migration once.
+const lockKey = 7468511
+
+// files holds a directory of migrations per dialect, sql/postgres,
+// sql/mysql and sql/sqlite. Postgres has every migration from 0001. MySQL and
+// SQLite start at 0010_baseline, one migration that creates the schema
+// Postgres has after 0010. Migrations after that are added to every
+// directory with the same version.
+//
+//go:embed sql
+var files embed.FS
+
+var (
+	fileName    = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	nonWordRuns = regexp.MustCompile(`\W+`)
+)
+
+// Migration is one numbered pair of files in a dialect's directory, e.g.
+// 0002_tenants.up.sql and 0002_tenants.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied, nil if pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the binary
+// for the dialect of db.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	sqlFiles, err := fs.Sub(files, path.Join("sql", dialect.Of(db).Name()))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(sqlFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Load reads the migration files at the root of fsys, sorted by version.
+// Every version needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	found := map[string]bool{}
+	key := func(version int, direction string) string {
+		return strconv.Itoa(version) + "." + direction
+	}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		found[key(version, match[3])] = true
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if !found[key(migration.Version, "up")] || !found[key(migration.Version, "down")] {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up() (int, error) {
+	applied := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		var err error
+		applied, err = m.up(conn, -1)
+		return err
+	})
+	return applied, err
+}
+
+// Down rolls back the last n applied migrations and returns how many ran.
+func (m *Migrator) Down(n int) (int, error) {
+	rolledBack := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		var err error
+		rolledBack, err = m.down(conn, n)
+		return err
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the last applied migration and applies it again.
+func (m *Migrator) Redo() error {
+	return m.withLock(func(conn *gorm.DB) error {
+		rolledBack, err := m.down(conn, 1)
+		if err != nil {
+			return err
+		}
+		if rolledBack == 0 {
+			return fmt.Errorf("no migration has been applied")
+		}
+		_, err = m.up(conn, 1)
+		return err
+	})
+}
+
+// Status lists every known migration with the time it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTable(m.DB); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.Migrations))
+	for i, migration := range m.Migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) up(conn *gorm.DB, limit int) (int, error) {
+	applied, err := m.applied(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.Migrations {
+		if count == limit {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *Migrator) down(conn *gorm.DB, n int) (int, error) {
+	var records []schemaMigration
+	err := conn.Order("version DESC").Limit(n).Find(&records).Error
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, record := range records {
+		migration, ok := m.find(record.Version)
+		if !ok {
+			return count, fmt.Errorf("migration %04d_%s is applied but its files are missing", record.Version, record.Name)
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, record.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// exec runs a migration file. It is passed without arguments so the driver
+// uses the simple query protocol, which allows several statements at once.
+func exec(tx *gorm.DB, sql string) error {
+	if strings.TrimSpace(sql) == "" {
+		return nil
+	}
+	return tx.Exec(sql).Error
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int]schemaMigration, error) {
+	var records []schemaMigration
+	if err := conn.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// ensureTable creates schema_migrations with the column types of the
+// database's dialect.
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	if conn.Migrator().HasTable(&schemaMigration{}) {
+		return nil
+	}
+	return conn.Migrator().CreateTable(&schemaMigration{})
+}
+
+// withLock runs fn on a single connection holding the migration lock. The
+// lock belongs to the session, so everything has to go through that
+// connection.
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	sqlDialect := dialect.Of(m.DB)
+	return m.DB.Connection(func(conn *gorm.DB) error {
+		if err := sqlDialect.Lock(conn, lockKey); err != nil {
+			return err
+		}
+		defer sqlDialect.Unlock(conn, lockKey)
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// Create writes an empty up and down file for a new migration into every
+// dialect directory under dir, numbered after the highest version already
+// there, and returns their paths.
+func Create(dir string, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = nonWordRuns.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	dialectDirs := []string{}
+	version := 1
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dialectDir := filepath.Join(dir, entry.Name())
+		migrations, err := Load(os.DirFS(dialectDir))
+		if err != nil {
+			return nil, err
+		}
+		if len(migrations) > 0 && migrations[len(migrations)-1].Version >= version {
+			version = migrations[len(migrations)-1].Version + 1
+		}
+		dialectDirs = append(dialectDirs, dialectDir)
+	}
+	if len(dialectDirs) == 0 {
+		return nil, fmt.Errorf("no dialect directories in %s", dir)
+	}
+
+	paths := []string{}
+	for _, dialectDir := range dialectDirs {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dialectDir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+			err := os.WriteFile(file, []byte{}, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, file)
+		}
+	}
+	return paths, nil
+}
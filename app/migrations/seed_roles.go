@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/fajaaro/dbo/app/models"
+	"gorm.io/gorm"
+)
+
+// SeedRoles creates the built-in roles and permissions and grants each role
+// the permissions listed in models.DefaultRoles. It is safe to run on every
+// boot; permissions granted by hand are left alone.
+func SeedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for roleName, permissionNames := range models.DefaultRoles {
+			role := models.Role{}
+			err := tx.Where(models.Role{Name: roleName}).FirstOrCreate(&role).Error
+			if err != nil {
+				return err
+			}
+
+			permissions := []models.Permission{}
+			for _, permissionName := range permissionNames {
+				permission := models.Permission{}
+				err := tx.Where(models.Permission{Name: permissionName}).FirstOrCreate(&permission).Error
+				if err != nil {
+					return err
+				}
+				permissions = append(permissions, permission)
+			}
+
+			err = tx.Model(&role).Association("Permissions").Append(permissions)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
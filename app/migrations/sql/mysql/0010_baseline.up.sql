@@ -0,0 +1,253 @@
+-- MySQL support starts from the schema as of Postgres migration 0010, so
+-- later migrations keep the same version on every database.
+-- CHECK constraints are enforced from MySQL 8.0.16.
+CREATE TABLE IF NOT EXISTS tenants (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    created_at datetime(3),
+    updated_at datetime(3)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS users (
+    id                      bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    tenant_id               bigint unsigned NOT NULL DEFAULT 0,
+    email                   varchar(255) NOT NULL UNIQUE,
+    name                    varchar(255),
+    password                varchar(255) NOT NULL,
+    totp_secret             varchar(255),
+    totp_enabled_at         datetime(3),
+    email_verified_at       datetime(3),
+    tokens_revoked_at       datetime(3),
+    disabled_at             datetime(3),
+    password_reset_required boolean NOT NULL DEFAULT false,
+    created_at              datetime(3),
+    updated_at              datetime(3),
+    KEY idx_users_tenant_id (tenant_id)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS customers (
+    id           bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    tenant_id    bigint unsigned NOT NULL DEFAULT 0,
+    name         varchar(255) NOT NULL,
+    email        varchar(255) NOT NULL,
+    phone_number varchar(255) NOT NULL,
+    gender       varchar(255) NOT NULL,
+    created_at   datetime(3),
+    updated_at   datetime(3),
+    KEY idx_customers_tenant_id (tenant_id),
+    UNIQUE KEY idx_customers_tenant_email (tenant_id, email)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS orders (
+    id             bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    tenant_id      bigint unsigned NOT NULL DEFAULT 0,
+    customer_id    bigint unsigned NOT NULL,
+    product_name   varchar(255) NOT NULL,
+    quantity       bigint NOT NULL,
+    total_price    double NOT NULL,
+    payment_status varchar(255),
+    paid_at        datetime(3),
+    created_at     datetime(3),
+    updated_at     datetime(3),
+    CONSTRAINT chk_orders_quantity CHECK (quantity >= 1),
+    CONSTRAINT chk_orders_total_price CHECK (total_price >= 0),
+    KEY idx_orders_tenant_id (tenant_id)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id             bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id        bigint unsigned NOT NULL,
+    token_hash     varchar(255) NOT NULL,
+    family_id      varchar(255) NOT NULL,
+    user_agent     text,
+    ip_address     varchar(255),
+    expires_at     datetime(3) NOT NULL,
+    revoked_at     datetime(3),
+    replaced_by_id bigint unsigned,
+    created_at     datetime(3),
+    updated_at     datetime(3),
+    UNIQUE KEY idx_refresh_tokens_token_hash (token_hash),
+    KEY idx_refresh_tokens_family_id (family_id),
+    KEY idx_refresh_tokens_user_id (user_id),
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti        varchar(255) PRIMARY KEY,
+    expires_at datetime(3) NOT NULL,
+    created_at datetime(3),
+    KEY idx_revoked_access_tokens_expires_at (expires_at)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id    bigint unsigned NOT NULL,
+    purpose    varchar(255) NOT NULL,
+    jti        varchar(255) NOT NULL,
+    expires_at datetime(3) NOT NULL,
+    used_at    datetime(3),
+    created_at datetime(3),
+    UNIQUE KEY idx_user_tokens_jti (jti),
+    KEY idx_user_tokens_user_id (user_id),
+    CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    `from`     varchar(255) NOT NULL,
+    `to`       varchar(255) NOT NULL,
+    subject    varchar(255) NOT NULL,
+    body       text NOT NULL,
+    created_at datetime(3),
+    KEY idx_outbox_messages_to (`to`)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS roles (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    name       varchar(255) NOT NULL UNIQUE,
+    created_at datetime(3),
+    updated_at datetime(3)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    name       varchar(255) NOT NULL UNIQUE,
+    created_at datetime(3),
+    updated_at datetime(3)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id bigint unsigned,
+    role_id bigint unsigned,
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id       bigint unsigned,
+    permission_id bigint unsigned,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id              bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    scope           varchar(255) NOT NULL,
+    identifier      varchar(255) NOT NULL,
+    failures        bigint NOT NULL DEFAULT 0,
+    last_failure_at datetime(3) NOT NULL,
+    locked_until    datetime(3),
+    created_at      datetime(3),
+    updated_at      datetime(3),
+    UNIQUE KEY idx_login_attempts_scope_identifier (scope, identifier)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id    bigint unsigned NOT NULL,
+    code_hash  varchar(255) NOT NULL,
+    used_at    datetime(3),
+    created_at datetime(3),
+    KEY idx_recovery_codes_user_id (user_id),
+    CONSTRAINT fk_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id      bigint unsigned NOT NULL,
+    name         varchar(255) NOT NULL,
+    prefix       varchar(255) NOT NULL,
+    key_hash     varchar(255) NOT NULL,
+    last_used_at datetime(3),
+    last_used_ip varchar(255),
+    expires_at   datetime(3),
+    revoked_at   datetime(3),
+    created_at   datetime(3),
+    updated_at   datetime(3),
+    UNIQUE KEY idx_api_keys_key_hash (key_hash),
+    UNIQUE KEY idx_api_keys_prefix (prefix),
+    KEY idx_api_keys_user_id (user_id),
+    CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS api_key_scopes (
+    api_key_id    bigint unsigned,
+    permission_id bigint unsigned,
+    PRIMARY KEY (api_key_id, permission_id),
+    CONSTRAINT fk_api_key_scopes_api_key FOREIGN KEY (api_key_id) REFERENCES api_keys (id) ON DELETE CASCADE,
+    CONSTRAINT fk_api_key_scopes_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id            bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id       bigint unsigned NOT NULL,
+    client_id     varchar(255) NOT NULL,
+    secret_hash   varchar(255),
+    confidential  boolean NOT NULL DEFAULT false,
+    name          varchar(255) NOT NULL,
+    redirect_uris text,
+    created_at    datetime(3),
+    updated_at    datetime(3),
+    UNIQUE KEY idx_oauth_clients_client_id (client_id),
+    KEY idx_oauth_clients_user_id (user_id),
+    CONSTRAINT fk_oauth_clients_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS oauth_client_scopes (
+    o_auth_client_id bigint unsigned,
+    permission_id    bigint unsigned,
+    PRIMARY KEY (o_auth_client_id, permission_id),
+    CONSTRAINT fk_oauth_client_scopes_o_auth_client FOREIGN KEY (o_auth_client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_client_scopes_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id              bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    oauth_client_id bigint unsigned NOT NULL,
+    user_id         bigint unsigned NOT NULL,
+    code_hash       varchar(255) NOT NULL,
+    redirect_uri    varchar(255) NOT NULL,
+    scope           varchar(255) NOT NULL,
+    code_challenge  varchar(255) NOT NULL,
+    expires_at      datetime(3) NOT NULL,
+    used_at         datetime(3),
+    created_at      datetime(3),
+    UNIQUE KEY idx_oauth_authorization_codes_code_hash (code_hash),
+    KEY idx_oauth_authorization_codes_o_auth_client_id (oauth_client_id),
+    KEY idx_oauth_authorization_codes_user_id (user_id),
+    CONSTRAINT fk_oauth_authorization_codes_oauth_client FOREIGN KEY (oauth_client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_authorization_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id                bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id           bigint unsigned NOT NULL,
+    family_id         varchar(255) NOT NULL,
+    user_agent        text,
+    ip_address        varchar(255),
+    last_refreshed_at datetime(3),
+    revoked_at        datetime(3),
+    created_at        datetime(3),
+    updated_at        datetime(3),
+    UNIQUE KEY idx_sessions_family_id (family_id),
+    KEY idx_sessions_user_id (user_id),
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    tenant_id   bigint unsigned NOT NULL,
+    actor_email varchar(255) NOT NULL,
+    user_id     bigint unsigned NOT NULL,
+    action      varchar(255) NOT NULL,
+    method      varchar(255),
+    path        text,
+    status      bigint,
+    ip_address  varchar(255),
+    created_at  datetime(3),
+    KEY idx_audit_logs_tenant_id (tenant_id),
+    KEY idx_audit_logs_user_id (user_id),
+    KEY idx_audit_logs_action (action)
+) ENGINE=InnoDB;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
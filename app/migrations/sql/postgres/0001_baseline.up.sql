@@ -0,0 +1,33 @@
+-- Schema as it was when the app only had users, customers and orders. The
+-- IF NOT EXISTS guards let databases created by GORM AutoMigrate adopt it.
+CREATE TABLE IF NOT EXISTS users (
+    id         bigserial PRIMARY KEY,
+    email      varchar NOT NULL UNIQUE,
+    password   varchar NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id           bigserial PRIMARY KEY,
+    name         varchar NOT NULL,
+    email        varchar NOT NULL UNIQUE,
+    phone_number varchar NOT NULL,
+    gender       varchar NOT NULL,
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id             bigserial PRIMARY KEY,
+    customer_id    bigint NOT NULL,
+    product_name   varchar NOT NULL,
+    quantity       bigint NOT NULL,
+    total_price    decimal NOT NULL,
+    payment_status varchar,
+    paid_at        timestamptz,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    CONSTRAINT chk_orders_quantity CHECK (quantity >= 1),
+    CONSTRAINT chk_orders_total_price CHECK (total_price >= 0)
+);
@@ -0,0 +1,9 @@
+-- Fails if two tenants have a customer with the same email.
+DROP INDEX IF EXISTS idx_customers_tenant_email;
+ALTER TABLE customers ADD CONSTRAINT customers_email_key UNIQUE (email);
+
+ALTER TABLE orders DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
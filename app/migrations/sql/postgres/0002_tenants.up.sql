@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id         bigserial PRIMARY KEY,
+    name       varchar NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_id ON customers (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_orders_tenant_id ON orders (tenant_id);
+
+-- Customer emails are only unique within a tenant.
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_tenant_email ON customers (tenant_id, email);
+
+-- Move rows created before tenants existed into a "Default" tenant.
+INSERT INTO tenants (name, created_at, updated_at)
+SELECT 'Default', now(), now()
+WHERE EXISTS (SELECT 1 FROM users WHERE tenant_id = 0)
+   OR EXISTS (SELECT 1 FROM customers WHERE tenant_id = 0)
+   OR EXISTS (SELECT 1 FROM orders WHERE tenant_id = 0);
+
+UPDATE users SET tenant_id = (SELECT max(id) FROM tenants WHERE name = 'Default') WHERE tenant_id = 0;
+UPDATE customers SET tenant_id = (SELECT max(id) FROM tenants WHERE name = 'Default') WHERE tenant_id = 0;
+UPDATE orders SET tenant_id = (SELECT max(id) FROM tenants WHERE name = 'Default') WHERE tenant_id = 0;
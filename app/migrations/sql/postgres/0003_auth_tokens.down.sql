@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS outbox_messages;
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS revoked_access_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_revoked_at;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,50 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id             bigserial PRIMARY KEY,
+    user_id        bigint NOT NULL,
+    token_hash     varchar NOT NULL,
+    family_id      varchar NOT NULL,
+    user_agent     varchar,
+    ip_address     varchar,
+    expires_at     timestamptz NOT NULL,
+    revoked_at     timestamptz,
+    replaced_by_id bigint,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti        varchar PRIMARY KEY,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL,
+    purpose    varchar NOT NULL,
+    jti        varchar NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_tokens_jti ON user_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id         bigserial PRIMARY KEY,
+    "from"     varchar NOT NULL,
+    "to"       varchar NOT NULL,
+    subject    varchar NOT NULL,
+    body       text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_to ON outbox_messages ("to");
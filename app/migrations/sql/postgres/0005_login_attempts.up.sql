@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id              bigserial PRIMARY KEY,
+    scope           varchar NOT NULL,
+    identifier      varchar NOT NULL,
+    failures        bigint NOT NULL DEFAULT 0,
+    last_failure_at timestamptz NOT NULL,
+    locked_until    timestamptz,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_scope_identifier ON login_attempts (scope, identifier);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           bigserial PRIMARY KEY,
+    user_id      bigint NOT NULL,
+    name         varchar NOT NULL,
+    prefix       varchar NOT NULL,
+    key_hash     varchar NOT NULL,
+    last_used_at timestamptz,
+    last_used_ip varchar,
+    expires_at   timestamptz,
+    revoked_at   timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS api_key_scopes (
+    api_key_id    bigint,
+    permission_id bigint,
+    PRIMARY KEY (api_key_id, permission_id),
+    CONSTRAINT fk_api_key_scopes_api_key FOREIGN KEY (api_key_id) REFERENCES api_keys (id) ON DELETE CASCADE,
+    CONSTRAINT fk_api_key_scopes_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
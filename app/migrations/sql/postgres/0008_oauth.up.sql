@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint NOT NULL,
+    client_id     varchar NOT NULL,
+    secret_hash   varchar,
+    confidential  boolean NOT NULL DEFAULT false,
+    name          varchar NOT NULL,
+    redirect_uris text,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    CONSTRAINT fk_oauth_clients_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_clients_client_id ON oauth_clients (client_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_user_id ON oauth_clients (user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_client_scopes (
+    o_auth_client_id bigint,
+    permission_id    bigint,
+    PRIMARY KEY (o_auth_client_id, permission_id),
+    CONSTRAINT fk_oauth_client_scopes_o_auth_client FOREIGN KEY (o_auth_client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_client_scopes_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id              bigserial PRIMARY KEY,
+    oauth_client_id bigint NOT NULL,
+    user_id         bigint NOT NULL,
+    code_hash       varchar NOT NULL,
+    redirect_uri    varchar NOT NULL,
+    scope           varchar NOT NULL,
+    code_challenge  varchar NOT NULL,
+    expires_at      timestamptz NOT NULL,
+    used_at         timestamptz,
+    created_at      timestamptz,
+    CONSTRAINT fk_oauth_authorization_codes_o_auth_client FOREIGN KEY (oauth_client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    CONSTRAINT fk_oauth_authorization_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_authorization_codes_code_hash ON oauth_authorization_codes (code_hash);
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_o_auth_client_id ON oauth_authorization_codes (oauth_client_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_user_id ON oauth_authorization_codes (user_id);
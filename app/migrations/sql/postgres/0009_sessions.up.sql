@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id                bigserial PRIMARY KEY,
+    user_id           bigint NOT NULL,
+    family_id         varchar NOT NULL,
+    user_agent        varchar,
+    ip_address        varchar,
+    last_refreshed_at timestamptz,
+    revoked_at        timestamptz,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
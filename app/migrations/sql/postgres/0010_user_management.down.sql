@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_logs;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS name;
@@ -0,0 +1,19 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS name varchar;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          bigserial PRIMARY KEY,
+    tenant_id   bigint NOT NULL,
+    actor_email varchar NOT NULL,
+    user_id     bigint NOT NULL,
+    action      varchar NOT NULL,
+    method      varchar,
+    path        varchar,
+    status      bigint,
+    ip_address  varchar,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id ON audit_logs (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
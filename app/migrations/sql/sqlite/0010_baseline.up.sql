@@ -0,0 +1,236 @@
+-- SQLite support starts from the schema as of Postgres migration 0010, so
+-- later migrations keep the same version on every database.
+CREATE TABLE IF NOT EXISTS tenants (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    name       text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id                      integer PRIMARY KEY AUTOINCREMENT,
+    tenant_id               integer NOT NULL DEFAULT 0,
+    email                   text NOT NULL UNIQUE,
+    name                    text,
+    password                text NOT NULL,
+    totp_secret             text,
+    totp_enabled_at         datetime,
+    email_verified_at       datetime,
+    tokens_revoked_at       datetime,
+    disabled_at             datetime,
+    password_reset_required boolean NOT NULL DEFAULT false,
+    created_at              datetime,
+    updated_at              datetime
+);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users (tenant_id);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    tenant_id    integer NOT NULL DEFAULT 0,
+    name         text NOT NULL,
+    email        text NOT NULL,
+    phone_number text NOT NULL,
+    gender       text NOT NULL,
+    created_at   datetime,
+    updated_at   datetime
+);
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_id ON customers (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_tenant_email ON customers (tenant_id, email);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    tenant_id      integer NOT NULL DEFAULT 0,
+    customer_id    integer NOT NULL,
+    product_name   text NOT NULL,
+    quantity       integer NOT NULL,
+    total_price    real NOT NULL,
+    payment_status text,
+    paid_at        datetime,
+    created_at     datetime,
+    updated_at     datetime,
+    CONSTRAINT chk_orders_quantity CHECK (quantity >= 1),
+    CONSTRAINT chk_orders_total_price CHECK (total_price >= 0)
+);
+CREATE INDEX IF NOT EXISTS idx_orders_tenant_id ON orders (tenant_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    user_id        integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash     text NOT NULL,
+    family_id      text NOT NULL,
+    user_agent     text,
+    ip_address     text,
+    expires_at     datetime NOT NULL,
+    revoked_at     datetime,
+    replaced_by_id integer,
+    created_at     datetime,
+    updated_at     datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti        text PRIMARY KEY,
+    expires_at datetime NOT NULL,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    user_id    integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    text NOT NULL,
+    jti        text NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at    datetime,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_tokens_jti ON user_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    "from"     text NOT NULL,
+    "to"       text NOT NULL,
+    subject    text NOT NULL,
+    body       text NOT NULL,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_to ON outbox_messages ("to");
+
+CREATE TABLE IF NOT EXISTS roles (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    name       text NOT NULL UNIQUE,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    name       text NOT NULL UNIQUE,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id integer REFERENCES users (id) ON DELETE CASCADE,
+    role_id integer REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id       integer REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id integer REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    scope           text NOT NULL,
+    identifier      text NOT NULL,
+    failures        integer NOT NULL DEFAULT 0,
+    last_failure_at datetime NOT NULL,
+    locked_until    datetime,
+    created_at      datetime,
+    updated_at      datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_attempts_scope_identifier ON login_attempts (scope, identifier);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    user_id    integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  text NOT NULL,
+    used_at    datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    user_id      integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         text NOT NULL,
+    prefix       text NOT NULL,
+    key_hash     text NOT NULL,
+    last_used_at datetime,
+    last_used_ip text,
+    expires_at   datetime,
+    revoked_at   datetime,
+    created_at   datetime,
+    updated_at   datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS api_key_scopes (
+    api_key_id    integer REFERENCES api_keys (id) ON DELETE CASCADE,
+    permission_id integer REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (api_key_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    user_id       integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id     text NOT NULL,
+    secret_hash   text,
+    confidential  boolean NOT NULL DEFAULT false,
+    name          text NOT NULL,
+    redirect_uris text,
+    created_at    datetime,
+    updated_at    datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_clients_client_id ON oauth_clients (client_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_user_id ON oauth_clients (user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_client_scopes (
+    o_auth_client_id integer REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    permission_id    integer REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (o_auth_client_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    oauth_client_id integer NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    user_id         integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash       text NOT NULL,
+    redirect_uri    text NOT NULL,
+    scope           text NOT NULL,
+    code_challenge  text NOT NULL,
+    expires_at      datetime NOT NULL,
+    used_at         datetime,
+    created_at      datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_authorization_codes_code_hash ON oauth_authorization_codes (code_hash);
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_o_auth_client_id ON oauth_authorization_codes (oauth_client_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_user_id ON oauth_authorization_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id                integer PRIMARY KEY AUTOINCREMENT,
+    user_id           integer NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    family_id         text NOT NULL,
+    user_agent        text,
+    ip_address        text,
+    last_refreshed_at datetime,
+    revoked_at        datetime,
+    created_at        datetime,
+    updated_at        datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    tenant_id   integer NOT NULL,
+    actor_email text NOT NULL,
+    user_id     integer NOT NULL,
+    action      text NOT NULL,
+    method      text,
+    path        text,
+    status      integer,
+    ip_address  text,
+    created_at  datetime
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id ON audit_logs (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey lets a user's batch jobs and integrations call the API without
+// logging in. Only a hash of the key is stored; Prefix is the public start of
+// the key so it can be recognized in listings and logs.
+type APIKey struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"not null;index"`
+	User       User         `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Name       string       `json:"name" gorm:"type:varchar;not null"`
+	Prefix     string       `json:"prefix" gorm:"type:varchar;uniqueIndex;not null"`
+	KeyHash    string       `json:"-" gorm:"type:varchar;uniqueIndex;not null"`
+	Scopes     []Permission `json:"scopes" gorm:"many2many:api_key_scopes;constraint:OnDelete:CASCADE"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	LastUsedIP string       `json:"last_used_ip" gorm:"type:varchar"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"default:null"`
+	UpdatedAt  time.Time    `json:"updated_at" gorm:"default:null"`
+}
+
+// HasScope reports whether the key was granted permission. Scopes must be
+// preloaded.
+func (k *APIKey) HasScope(permission string) bool {
+	for _, scope := range k.Scopes {
+		if scope.Name == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AuditImpersonationStart  = "impersonation.start"
+	AuditImpersonatedRequest = "impersonation.request"
+)
+
+// AuditLog records who did what. ActorEmail is the user behind the action and
+// UserID the account it was done as; they differ while an admin impersonates
+// a user.
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TenantID   uint      `json:"tenant_id" gorm:"not null;index"`
+	ActorEmail string    `json:"actor_email" gorm:"type:varchar;not null"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"type:varchar;not null;index"`
+	Method     string    `json:"method" gorm:"type:varchar"`
+	Path       string    `json:"path" gorm:"type:varchar"`
+	Status     int       `json:"status"`
+	IPAddress  string    `json:"ip_address" gorm:"type:varchar"`
+	CreatedAt  time.Time `json:"created_at"`
+}
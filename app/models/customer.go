@@ -6,8 +6,9 @@ import (
 
 type Customer struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	TenantID    uint      `json:"tenant_id" gorm:"not null;default:0;index;uniqueIndex:idx_customers_tenant_email"`
 	Name        string    `json:"name" gorm:"type:varchar;not null"`
-	Email       string    `json:"email" gorm:"type:varchar;unique;not null"`
+	Email       string    `json:"email" gorm:"type:varchar;not null;uniqueIndex:idx_customers_tenant_email"`
 	PhoneNumber string    `json:"phone_number" gorm:"type:varchar;not null"`
 	Gender      string    `json:"gender" gorm:"type:varchar;not null"`
 	CreatedAt   time.Time `json:"created_at" gorm:"default:null"`
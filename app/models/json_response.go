@@ -4,4 +4,5 @@ type JsonResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data"`
 	Error   *string     `json:"error"`
+	Code    *string     `json:"code,omitempty"`
 }
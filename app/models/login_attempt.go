@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	LoginAttemptEmail = "email"
+	LoginAttemptIP    = "ip"
+)
+
+// LoginAttempt counts recent failed logins for one email address or one
+// client IP. It lives in the database so every replica sees the same counts.
+type LoginAttempt struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Scope         string     `json:"scope" gorm:"type:varchar;not null;uniqueIndex:idx_login_attempts_scope_identifier"`
+	Identifier    string     `json:"identifier" gorm:"type:varchar;not null;uniqueIndex:idx_login_attempts_scope_identifier"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"not null"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"default:null"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"default:null"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthClient is a third-party application registered by a user. Confidential
+// clients authenticate with a secret, of which only a hash is kept. Tokens
+// from the client_credentials grant act on behalf of the registering user.
+type OAuthClient struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	UserID       uint         `json:"user_id" gorm:"not null;index"`
+	User         User         `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	ClientID     string       `json:"client_id" gorm:"type:varchar;uniqueIndex;not null"`
+	SecretHash   string       `json:"-" gorm:"type:varchar"`
+	Confidential bool         `json:"confidential" gorm:"not null;default:false"`
+	Name         string       `json:"name" gorm:"type:varchar;not null"`
+	RedirectURIs []string     `json:"redirect_uris" gorm:"column:redirect_uris;type:text;serializer:json"`
+	Scopes       []Permission `json:"scopes" gorm:"many2many:oauth_client_scopes;constraint:OnDelete:CASCADE"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"default:null"`
+	UpdatedAt    time.Time    `json:"updated_at" gorm:"default:null"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// HasScope reports whether the client may ask for permission. Scopes must be
+// preloaded.
+func (c *OAuthClient) HasScope(permission string) bool {
+	for _, scope := range c.Scopes {
+		if scope.Name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRedirectURI reports whether uri is one of the registered redirect URIs.
+// They are compared exactly, as RFC 6749 recommends.
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, redirectURI := range c.RedirectURIs {
+		if redirectURI == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizationCode is the short-lived code handed to a client after the
+// user consented. Scope holds the granted permissions separated by spaces.
+type OAuthAuthorizationCode struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	OAuthClientID uint        `json:"oauth_client_id" gorm:"column:oauth_client_id;not null;index"`
+	OAuthClient   OAuthClient `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID        uint        `json:"user_id" gorm:"not null;index"`
+	User          User        `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CodeHash      string      `json:"-" gorm:"type:varchar;uniqueIndex;not null"`
+	RedirectURI   string      `json:"redirect_uri" gorm:"type:varchar;not null"`
+	Scope         string      `json:"scope" gorm:"type:varchar;not null"`
+	CodeChallenge string      `json:"-" gorm:"type:varchar;not null"`
+	ExpiresAt     time.Time   `json:"expires_at" gorm:"not null"`
+	UsedAt        *time.Time  `json:"used_at"`
+	CreatedAt     time.Time   `json:"created_at" gorm:"default:null"`
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
@@ -6,6 +6,7 @@ import (
 
 type Order struct {
 	ID            uint       `json:"id" gorm:"primaryKey"`
+	TenantID      uint       `json:"tenant_id" gorm:"not null;default:0;index"`
 	CustomerID    uint       `json:"customer_id" gorm:"constraint:OnDelete:CASCADE;not null"`
 	ProductName   string     `json:"product_name" gorm:"type:varchar;not null"`
 	Quantity      int        `json:"quantity" gorm:"not null;check:quantity >= 1"`
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type OutboxMessage struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	From      string    `json:"from" gorm:"type:varchar;not null"`
+	To        string    `json:"to" gorm:"type:varchar;not null;index"`
+	Subject   string    `json:"subject" gorm:"type:varchar;not null"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:null"`
+}
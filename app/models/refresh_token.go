@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	User         User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	TokenHash    string     `json:"-" gorm:"type:varchar;uniqueIndex;not null"`
+	FamilyID     string     `json:"family_id" gorm:"type:varchar;index;not null"`
+	UserAgent    string     `json:"user_agent" gorm:"type:varchar"`
+	IPAddress    string     `json:"ip_address" gorm:"type:varchar"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"default:null"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"default:null"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+type RevokedAccessToken struct {
+	JTI       string    `json:"jti" gorm:"type:varchar;primaryKey"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:null"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+const (
+	PermissionCustomersRead   = "customers:read"
+	PermissionCustomersWrite  = "customers:write"
+	PermissionCustomersDelete = "customers:delete"
+	PermissionOrdersRead      = "orders:read"
+	PermissionOrdersWrite     = "orders:write"
+	PermissionOrdersDelete    = "orders:delete"
+	PermissionRolesManage     = "roles:manage"
+	PermissionUsersManage     = "users:manage"
+)
+
+// DefaultRoles lists the permissions each built-in role is seeded with.
+var DefaultRoles = map[string][]string{
+	RoleAdmin: {
+		PermissionCustomersRead, PermissionCustomersWrite, PermissionCustomersDelete,
+		PermissionOrdersRead, PermissionOrdersWrite, PermissionOrdersDelete,
+		PermissionRolesManage, PermissionUsersManage,
+	},
+	RoleOperator: {
+		PermissionCustomersRead, PermissionCustomersWrite,
+		PermissionOrdersRead, PermissionOrdersWrite,
+	},
+	RoleViewer: {
+		PermissionCustomersRead,
+		PermissionOrdersRead,
+	},
+}
+
+type Role struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"type:varchar;unique;not null"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"default:null"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"default:null"`
+}
+
+type Permission struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar;unique;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:null"`
+}
+
+// RoleNames returns the names of the user's loaded roles.
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// HasPermission reports whether any of the user's loaded roles grants
+// permission. Roles must be preloaded with their permissions.
+func (u *User) HasPermission(permission string) bool {
+	for _, role := range u.Roles {
+		for _, p := range role.Permissions {
+			if p.Name == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one login of a user, e.g. one browser or device. Its refresh
+// tokens share FamilyID, and access tokens carry it in the sid claim.
+type Session struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	User            User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	FamilyID        string     `json:"-" gorm:"type:varchar;uniqueIndex;not null"`
+	UserAgent       string     `json:"user_agent" gorm:"type:varchar"`
+	IPAddress       string     `json:"ip_address" gorm:"type:varchar"`
+	LastRefreshedAt *time.Time `json:"last_refreshed_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	Current         bool       `json:"current" gorm:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type Tenant struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:null"`
+}
@@ -5,9 +5,20 @@ import (
 )
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Email     string    `json:"email" gorm:"type:varchar;unique;not null"`
-	Password  string    `json:"password" gorm:"type:varchar;not null"`
-	CreatedAt time.Time `json:"created_at" gorm:"default:null"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"default:null"`
+	ID                    uint           `json:"id" gorm:"primaryKey"`
+	TenantID              uint           `json:"tenant_id" gorm:"not null;default:0;index"`
+	Email                 string         `json:"email" gorm:"type:varchar;unique;not null"`
+	Name                  string         `json:"name" gorm:"type:varchar"`
+	Password              string         `json:"-" gorm:"type:varchar;not null"`
+	TOTPSecret            string         `json:"-" gorm:"type:varchar"`
+	TOTPEnabledAt         *time.Time     `json:"totp_enabled_at"`
+	TOTPLastStep          int64          `json:"-" gorm:"not null;default:0"`
+	RecoveryCodes         []RecoveryCode `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	EmailVerifiedAt       *time.Time     `json:"email_verified_at"`
+	TokensRevokedAt       *time.Time     `json:"tokens_revoked_at"`
+	DisabledAt            *time.Time     `json:"disabled_at"`
+	PasswordResetRequired bool           `json:"password_reset_required" gorm:"not null;default:false"`
+	Roles                 []Role         `json:"roles" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
+	CreatedAt             time.Time      `json:"created_at" gorm:"default:null"`
+	UpdatedAt             time.Time      `json:"updated_at" gorm:"default:null"`
 }
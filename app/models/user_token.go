@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	UserTokenEmailVerification = "email_verification"
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenMFAPending        = "mfa_pending"
+)
+
+// UserToken records a single-use token mailed to a user or, for mfa_pending,
+// returned by a login that still needs a second factor. The token itself is
+// a signed JWT; only its jti is stored so it can be used once.
+type UserToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Purpose   string     `json:"purpose" gorm:"type:varchar;not null"`
+	JTI       string     `json:"-" gorm:"type:varchar;uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"default:null"`
+}
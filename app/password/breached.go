@@ -0,0 +1,137 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed common_passwords.txt
+var bundled embed.FS
+
+// RangeSource answers k-anonymity range queries like the Pwned Passwords API:
+// given the first 5 hex characters of a password's SHA-1, it returns the
+// remaining 35 characters of every known hash with that prefix. Only the
+// prefix ever leaves the caller.
+type RangeSource interface {
+	Range(prefix string) ([]string, error)
+}
+
+// IsBreached reports whether password is known to source.
+func IsBreached(source RangeSource, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := source.Range(hash[:5])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if suffix == hash[5:] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hashList is a RangeSource over full SHA-1 hashes held in memory.
+type hashList map[string][]string
+
+func (l hashList) Range(prefix string) ([]string, error) {
+	return l[strings.ToUpper(prefix)], nil
+}
+
+// readHashList reads one SHA-1 hash per line, optionally followed by
+// ":count" as in the Pwned Passwords downloads.
+func readHashList(r io.Reader) (hashList, error) {
+	list := hashList{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) != 40 {
+			continue
+		}
+		hash = strings.ToUpper(hash)
+		list[hash[:5]] = append(list[hash[:5]], hash[5:])
+	}
+	return list, scanner.Err()
+}
+
+// Bundled returns the list of common passwords shipped with dbo.
+func Bundled() RangeSource {
+	f, err := bundled.Open("common_passwords.txt")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	list, err := readHashList(f)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// dirSource reads range files from a directory: one file per prefix, named
+// "<PREFIX>" or "<PREFIX>.txt", holding "SUFFIX:COUNT" lines. That is the
+// layout the Pwned Passwords downloader writes.
+type dirSource struct {
+	dir string
+}
+
+func (s dirSource) Range(prefix string) ([]string, error) {
+	prefix = strings.ToUpper(prefix)
+	f, err := os.Open(filepath.Join(s.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(filepath.Join(s.dir, prefix))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var suffixes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		suffix, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if suffix != "" {
+			suffixes = append(suffixes, strings.ToUpper(suffix))
+		}
+	}
+	return suffixes, scanner.Err()
+}
+
+// Load returns the RangeSource at path: a directory of range files, or a
+// single file of full hashes in the format of the bundled list. An empty path
+// gives the bundled list.
+func Load(path string) (RangeSource, error) {
+	if path == "" {
+		return Bundled(), nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return dirSource{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readHashList(f)
+}
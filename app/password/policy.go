@@ -0,0 +1,109 @@
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BcryptMaxLength is the number of bytes bcrypt hashes; anything after it is
+// silently ignored.
+const BcryptMaxLength = 72
+
+// Policy is the set of rules a new password has to pass. Breached is
+// optional; when set, passwords found in it are refused.
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Breached      RangeSource
+}
+
+// PolicyError names the rule a password failed. Code is sent to clients next
+// to the message.
+type PolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// Validate checks password against the policy. email is the account's email;
+// the password may not contain it or its local part. It returns a
+// *PolicyError for the first rule that fails, or another error when the
+// breached password source can't be read.
+func (p Policy) Validate(password string, email string) error {
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > BcryptMaxLength {
+		maxLength = BcryptMaxLength
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		return &PolicyError{Code: "password_too_short", Message: "Password must be at least " + strconv.Itoa(p.MinLength) + " characters long"}
+	}
+	if len(password) > maxLength {
+		return &PolicyError{Code: "password_too_long", Message: "Password must be at most " + strconv.Itoa(maxLength) + " bytes long"}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		return &PolicyError{Code: "password_missing_uppercase", Message: "Password must contain an uppercase letter"}
+	}
+	if p.RequireLower && !hasLower {
+		return &PolicyError{Code: "password_missing_lowercase", Message: "Password must contain a lowercase letter"}
+	}
+	if p.RequireDigit && !hasDigit {
+		return &PolicyError{Code: "password_missing_digit", Message: "Password must contain a digit"}
+	}
+	if p.RequireSymbol && !hasSymbol {
+		return &PolicyError{Code: "password_missing_symbol", Message: "Password must contain a symbol"}
+	}
+
+	if containsEmail(password, email) {
+		return &PolicyError{Code: "password_contains_email", Message: "Password must not contain the email address"}
+	}
+
+	if p.Breached != nil {
+		breached, err := IsBreached(p.Breached, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return &PolicyError{Code: "password_breached", Message: "Password is too common or has appeared in a data breach"}
+		}
+	}
+
+	return nil
+}
+
+// containsEmail reports whether password contains email or, when it is long
+// enough to matter, the part before the @.
+func containsEmail(password string, email string) bool {
+	email = strings.ToLower(email)
+	if email == "" {
+		return false
+	}
+	password = strings.ToLower(password)
+	if strings.Contains(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(password, local)
+}
@@ -2,48 +2,149 @@ package routers
 
 import (
 	"github.com/fajaaro/dbo/app/controllers"
+	"github.com/fajaaro/dbo/app/metrics"
 	"github.com/fajaaro/dbo/app/middlewares"
+	"github.com/fajaaro/dbo/app/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type API struct {
 	AuthRepo     controllers.AuthRepo
 	OrderRepo    controllers.OrderRepo
 	CustomerRepo controllers.CustomerRepo
+	RoleRepo     controllers.RoleRepo
+	TenantRepo   controllers.TenantRepo
+	UserRepo     controllers.UserRepo
+	APIKeyRepo   controllers.APIKeyRepo
+	OAuthRepo    controllers.OAuthRepo
 }
 
-func SetupRouter(AuthRepo controllers.AuthRepo, OrderRepo controllers.OrderRepo, CustomerRepo controllers.CustomerRepo) *gin.Engine {
+// SetupRouter builds the API on db, the one database handle shared by every
+// controller and middleware.
+func SetupRouter(db *gorm.DB) *gin.Engine {
 	r := gin.New()
 	api := API{
-		AuthRepo,
-		OrderRepo,
-		CustomerRepo,
+		*controllers.AuthController(db),
+		*controllers.OrderController(db),
+		*controllers.CustomerController(db),
+		*controllers.RoleController(db),
+		*controllers.TenantController(db),
+		*controllers.UserController(db),
+		*controllers.APIKeyController(db),
+		*controllers.OAuthController(db),
 	}
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
+	// X-Forwarded-For only counts when it comes from a trusted proxy; with
+	// none configured, the client IP is the address of the connection. The
+	// list was validated when the configuration was loaded.
+	if err := r.SetTrustedProxies(controllers.Config.HTTP.TrustedProxyList()); err != nil {
+		panic(err)
+	}
+	jwt := middlewares.JWT(db)
+	r.Use(middlewares.Logger())
+	r.Use(middlewares.RequestID())
+	metricsConfig := controllers.Config.Metrics
+	if metricsConfig.Enabled {
+		r.Use(middlewares.Metrics())
+	}
+	r.Use(middlewares.Recovery())
 	// r.Use(middleware.CORSMiddleware(), middleware.ClientInfo())
 
+	r.GET("/.well-known/jwks.json", api.AuthRepo.JWKS)
+	// With metrics.address set, serve mounts the metrics on that port instead.
+	if metricsConfig.Enabled && metricsConfig.Address == "" {
+		r.GET(metricsConfig.Path, gin.WrapH(metrics.Handler()))
+	}
+
 	authRoutes := r.Group("")
 	authRoutes.POST("/api/auth/register", api.AuthRepo.Register)
 	authRoutes.POST("/api/auth/login", api.AuthRepo.Login)
 	authRoutes.POST("/api/auth/refresh-token", api.AuthRepo.RefreshToken)
 	authRoutes.POST("/api/auth/match-token", api.AuthRepo.MatchToken)
+	authRoutes.POST("/api/auth/revoke-token", api.AuthRepo.RevokeToken)
+	authRoutes.POST("/api/auth/verify-email/request", api.AuthRepo.RequestEmailVerification)
+	authRoutes.POST("/api/auth/verify-email/confirm", api.AuthRepo.ConfirmEmailVerification)
+	authRoutes.POST("/api/auth/password-reset/request", api.AuthRepo.RequestPasswordReset)
+	authRoutes.POST("/api/auth/password-reset/confirm", api.AuthRepo.ConfirmPasswordReset)
+	authRoutes.POST("/api/auth/mfa/verify", api.AuthRepo.VerifyMFA)
+
+	sessionRoutes := r.Group("")
+	sessionRoutes.Use(jwt, middlewares.RequireAccessToken())
+	sessionRoutes.POST("/api/auth/logout", api.AuthRepo.Logout)
+	sessionRoutes.POST("/api/auth/logout-all", api.AuthRepo.LogoutAll)
+	sessionRoutes.GET("/api/auth/sessions", api.AuthRepo.GetSessions)
+	sessionRoutes.DELETE("/api/auth/sessions/:id", api.AuthRepo.RevokeSession)
+	sessionRoutes.POST("/api/auth/mfa/enroll", api.AuthRepo.EnrollMFA)
+	sessionRoutes.POST("/api/auth/mfa/confirm", api.AuthRepo.ConfirmMFA)
+	sessionRoutes.POST("/api/auth/mfa/disable", api.AuthRepo.DisableMFA)
+
+	meRoutes := r.Group("")
+	meRoutes.Use(jwt)
+	meRoutes.GET("/api/me", api.AuthRepo.GetMe)
+	meRoutes.PATCH("/api/me", middlewares.RequireAccessToken(), api.AuthRepo.UpdateMe)
+	meRoutes.POST("/api/me/password", middlewares.RequireAccessToken(), api.AuthRepo.ChangePassword)
 
 	orderRoutes := r.Group("")
-	orderRoutes.Use(middlewares.JWT())
-	orderRoutes.GET("/api/orders", api.OrderRepo.GetAllOrders)
-	orderRoutes.GET("/api/orders/:id", api.OrderRepo.GetOrderDetail)
-	orderRoutes.POST("/api/orders", api.OrderRepo.InsertOrder)
-	orderRoutes.PUT("/api/orders/:id", api.OrderRepo.UpdateOrder)
-	orderRoutes.DELETE("/api/orders/:id", api.OrderRepo.DeleteOrder)
+	orderRoutes.Use(jwt)
+	orderRoutes.GET("/api/orders", middlewares.RequirePermission(models.PermissionOrdersRead), api.OrderRepo.GetAllOrders)
+	orderRoutes.GET("/api/orders/:id", middlewares.RequirePermission(models.PermissionOrdersRead), api.OrderRepo.GetOrderDetail)
+	orderRoutes.POST("/api/orders", middlewares.RequirePermission(models.PermissionOrdersWrite), api.OrderRepo.InsertOrder)
+	orderRoutes.PUT("/api/orders/:id", middlewares.RequirePermission(models.PermissionOrdersWrite), api.OrderRepo.UpdateOrder)
+	orderRoutes.DELETE("/api/orders/:id", middlewares.RequirePermission(models.PermissionOrdersDelete), api.OrderRepo.DeleteOrder)
 
 	customerRoutes := r.Group("")
-	customerRoutes.Use(middlewares.JWT())
-	customerRoutes.GET("/api/customers", api.CustomerRepo.GetAllCustomers)
-	customerRoutes.GET("/api/customers/:id", api.CustomerRepo.GetCustomerDetail)
-	customerRoutes.POST("/api/customers", api.CustomerRepo.InsertCustomer)
-	customerRoutes.PUT("/api/customers/:id", api.CustomerRepo.UpdateCustomer)
-	customerRoutes.DELETE("/api/customers/:id", api.CustomerRepo.DeleteCustomer)
+	customerRoutes.Use(jwt)
+	customerRoutes.GET("/api/customers", middlewares.RequirePermission(models.PermissionCustomersRead), api.CustomerRepo.GetAllCustomers)
+	customerRoutes.GET("/api/customers/:id", middlewares.RequirePermission(models.PermissionCustomersRead), api.CustomerRepo.GetCustomerDetail)
+	customerRoutes.POST("/api/customers", middlewares.RequirePermission(models.PermissionCustomersWrite), api.CustomerRepo.InsertCustomer)
+	customerRoutes.PUT("/api/customers/:id", middlewares.RequirePermission(models.PermissionCustomersWrite), api.CustomerRepo.UpdateCustomer)
+	customerRoutes.DELETE("/api/customers/:id", middlewares.RequirePermission(models.PermissionCustomersDelete), api.CustomerRepo.DeleteCustomer)
+
+	roleRoutes := r.Group("")
+	roleRoutes.Use(jwt, middlewares.RejectImpersonation(), middlewares.RequirePermission(models.PermissionRolesManage))
+	roleRoutes.GET("/api/roles", api.RoleRepo.GetAllRoles)
+	roleRoutes.POST("/api/users/:id/roles", api.RoleRepo.GrantRole)
+	roleRoutes.DELETE("/api/users/:id/roles/:role", api.RoleRepo.RevokeRole)
+
+	tenantRoutes := r.Group("")
+	tenantRoutes.Use(jwt)
+	tenantRoutes.GET("/api/tenant", api.TenantRepo.GetTenant)
+	tenantRoutes.POST("/api/tenant/users", middlewares.RejectImpersonation(), middlewares.RequirePermission(models.PermissionUsersManage), api.TenantRepo.CreateTenantUser)
+
+	userRoutes := r.Group("")
+	userRoutes.Use(jwt, middlewares.RejectImpersonation(), middlewares.RequirePermission(models.PermissionUsersManage))
+	userRoutes.GET("/api/users", api.UserRepo.GetAllUsers)
+	userRoutes.GET("/api/users/:id", api.UserRepo.GetUserDetail)
+	userRoutes.PUT("/api/users/:id/email", api.UserRepo.UpdateUserEmail)
+	userRoutes.DELETE("/api/users/:id", api.UserRepo.DeleteUser)
+	userRoutes.POST("/api/users/:id/disable", api.UserRepo.DisableUser)
+	userRoutes.POST("/api/users/:id/enable", api.UserRepo.EnableUser)
+	userRoutes.POST("/api/users/:id/force-password-reset", api.UserRepo.ForcePasswordReset)
+	userRoutes.POST("/api/users/:id/unlock", api.UserRepo.UnlockUser)
+	userRoutes.POST("/api/users/:id/impersonate", middlewares.RequireAccessToken(), api.UserRepo.Impersonate)
+	userRoutes.GET("/api/audit-logs", api.UserRepo.GetAuditLogs)
+	userRoutes.GET("/api/users/:id/sessions", api.UserRepo.GetUserSessions)
+	userRoutes.DELETE("/api/users/:id/sessions/:session_id", api.UserRepo.RevokeUserSession)
+
+	apiKeyRoutes := r.Group("")
+	apiKeyRoutes.Use(jwt, middlewares.RequireAccessToken())
+	apiKeyRoutes.GET("/api/api-keys", api.APIKeyRepo.GetAllAPIKeys)
+	apiKeyRoutes.POST("/api/api-keys", api.APIKeyRepo.CreateAPIKey)
+	apiKeyRoutes.PUT("/api/api-keys/:id", api.APIKeyRepo.UpdateAPIKey)
+	apiKeyRoutes.DELETE("/api/api-keys/:id", api.APIKeyRepo.RevokeAPIKey)
+
+	oauthRoutes := r.Group("")
+	oauthRoutes.POST("/oauth/token", api.OAuthRepo.Token)
+	oauthRoutes.POST("/oauth/introspect", api.OAuthRepo.Introspect)
+	oauthRoutes.POST("/oauth/revoke", api.OAuthRepo.Revoke)
+
+	oauthConsentRoutes := r.Group("")
+	oauthConsentRoutes.Use(jwt, middlewares.RequireAccessToken())
+	oauthConsentRoutes.GET("/oauth/authorize", api.OAuthRepo.Authorize)
+	oauthConsentRoutes.POST("/oauth/authorize", api.OAuthRepo.ApproveAuthorization)
+	oauthConsentRoutes.GET("/api/oauth/clients", api.OAuthRepo.GetAllOAuthClients)
+	oauthConsentRoutes.POST("/api/oauth/clients", api.OAuthRepo.CreateOAuthClient)
+	oauthConsentRoutes.DELETE("/api/oauth/clients/:id", api.OAuthRepo.DeleteOAuthClient)
 
 	return r
 }
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/store"
+)
+
+type CustomerInput struct {
+	Name        string
+	Email       string
+	PhoneNumber string
+	Gender      string
+}
+
+// CustomerService holds the rules for customers: gender is male or female,
+// emails are unique within a tenant, and deleting a customer deletes their
+// orders.
+type CustomerService struct {
+	Customers store.CustomerStore
+	Orders    store.OrderStore
+}
+
+func NewCustomerService(customers store.CustomerStore, orders store.OrderStore) *CustomerService {
+	return &CustomerService{Customers: customers, Orders: orders}
+}
+
+func normalizeGender(gender string) (string, error) {
+	gender = strings.ToLower(gender)
+	if gender != "male" && gender != "female" {
+		return "", ErrInvalidGender
+	}
+	return gender, nil
+}
+
+func (s *CustomerService) List(ctx context.Context, tenantID uint, page store.Page) ([]models.Customer, int64, error) {
+	return s.Customers.List(ctx, tenantID, page)
+}
+
+func (s *CustomerService) Get(ctx context.Context, tenantID uint, id uint) (*models.Customer, error) {
+	customer, err := s.Customers.Get(ctx, tenantID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrCustomerNotFound
+	}
+	return customer, err
+}
+
+func (s *CustomerService) Create(ctx context.Context, tenantID uint, input CustomerInput) (*models.Customer, error) {
+	gender, err := normalizeGender(input.Gender)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.Customers.EmailExists(ctx, tenantID, input.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrEmailExists
+	}
+
+	customer := &models.Customer{
+		TenantID:    tenantID,
+		Name:        input.Name,
+		Email:       input.Email,
+		PhoneNumber: input.PhoneNumber,
+		Gender:      gender,
+	}
+	if err := s.Customers.Create(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// Update changes everything but the email, which identifies the customer.
+func (s *CustomerService) Update(ctx context.Context, tenantID uint, id uint, input CustomerInput) (*models.Customer, error) {
+	gender, err := normalizeGender(input.Gender)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	customer.Name = input.Name
+	customer.PhoneNumber = input.PhoneNumber
+	customer.Gender = gender
+	if err := s.Customers.Update(ctx, customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+func (s *CustomerService) Delete(ctx context.Context, tenantID uint, id uint) error {
+	customer, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Orders.DeleteByCustomer(ctx, tenantID, customer.ID); err != nil {
+		return err
+	}
+	return s.Customers.Delete(ctx, customer)
+}
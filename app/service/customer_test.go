@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/store"
+)
+
+func newCustomerService() *CustomerService {
+	return NewCustomerService(store.NewMemoryCustomerStore(), store.NewMemoryOrderStore())
+}
+
+func TestCreateCustomer(t *testing.T) {
+	ctx := context.Background()
+	s := newCustomerService()
+	if _, err := s.Create(ctx, 1, CustomerInput{Name: "Taken", Email: "taken@example.com", Gender: "male"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		tenantID   uint
+		input      CustomerInput
+		wantErr    error
+		wantGender string
+	}{
+		{
+			name:       "valid",
+			tenantID:   1,
+			input:      CustomerInput{Name: "Cust 1", Email: "cust1@example.com", Gender: "female"},
+			wantGender: "female",
+		},
+		{
+			name:       "gender in any case",
+			tenantID:   1,
+			input:      CustomerInput{Name: "Cust 2", Email: "cust2@example.com", Gender: "MALE"},
+			wantGender: "male",
+		},
+		{
+			name:     "unknown gender",
+			tenantID: 1,
+			input:    CustomerInput{Name: "Cust 3", Email: "cust3@example.com", Gender: "other"},
+			wantErr:  ErrInvalidGender,
+		},
+		{
+			name:     "email taken in the tenant",
+			tenantID: 1,
+			input:    CustomerInput{Name: "Cust 4", Email: "taken@example.com", Gender: "male"},
+			wantErr:  ErrEmailExists,
+		},
+		{
+			name:       "email taken in another tenant",
+			tenantID:   2,
+			input:      CustomerInput{Name: "Cust 5", Email: "taken@example.com", Gender: "male"},
+			wantGender: "male",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := s.Create(ctx, tt.tenantID, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if customer.TenantID != tt.tenantID || customer.Gender != tt.wantGender {
+				t.Errorf("got tenant %d and gender %q, want %d and %q", customer.TenantID, customer.Gender, tt.tenantID, tt.wantGender)
+			}
+		})
+	}
+}
+
+func TestCustomersStayInTheirTenant(t *testing.T) {
+	ctx := context.Background()
+	s := newCustomerService()
+	customer, err := s.Create(ctx, 1, CustomerInput{Name: "Cust 1", Email: "cust1@example.com", Gender: "male"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := s.Get(ctx, 2, customer.ID)
+			return err
+		}},
+		{"update", func() error {
+			_, err := s.Update(ctx, 2, customer.ID, CustomerInput{Name: "Mine now", Gender: "male"})
+			return err
+		}},
+		{"delete", func() error {
+			return s.Delete(ctx, 2, customer.ID)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrCustomerNotFound) {
+				t.Fatalf("got error %v, want %v", err, ErrCustomerNotFound)
+			}
+		})
+	}
+
+	if got, err := s.Get(ctx, 1, customer.ID); err != nil || got.Name != "Cust 1" {
+		t.Fatalf("customer is %+v, %v after the other tenant's calls", got, err)
+	}
+}
+
+func TestUpdateCustomerKeepsTheEmail(t *testing.T) {
+	ctx := context.Background()
+	s := newCustomerService()
+	customer, err := s.Create(ctx, 1, CustomerInput{Name: "Cust 1", Email: "cust1@example.com", Gender: "male"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.Update(ctx, 1, customer.ID, CustomerInput{
+		Name:        "Cust One",
+		Email:       "other@example.com",
+		PhoneNumber: "087786552751",
+		Gender:      "Female",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "cust1@example.com" || updated.Name != "Cust One" || updated.Gender != "female" {
+		t.Errorf("got %+v", updated)
+	}
+
+	_, err = s.Update(ctx, 1, customer.ID, CustomerInput{Name: "Cust One", Gender: "other"})
+	if !errors.Is(err, ErrInvalidGender) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidGender)
+	}
+}
+
+func TestDeleteCustomerDeletesTheirOrders(t *testing.T) {
+	ctx := context.Background()
+	s := newCustomerService()
+	var customers []*models.Customer
+	for _, email := range []string{"cust1@example.com", "cust2@example.com"} {
+		customer, err := s.Create(ctx, 1, CustomerInput{Name: "Cust", Email: email, Gender: "male"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		customers = append(customers, customer)
+		err = s.Orders.Create(ctx, &models.Order{TenantID: 1, CustomerID: customer.ID, ProductName: "Book", PaymentStatus: "unpaid"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Delete(ctx, 1, customers[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(ctx, 1, customers[0].ID); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("got error %v for the deleted customer, want %v", err, ErrCustomerNotFound)
+	}
+	orders, count, err := s.Orders.List(ctx, 1, store.Page{Limit: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || orders[0].CustomerID != customers[1].ID {
+		t.Errorf("got orders %+v, want only the one of the other customer", orders)
+	}
+}
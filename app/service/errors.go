@@ -0,0 +1,22 @@
+package service
+
+// Error is a business rule a request broke. Its message is meant for the
+// client: handlers answer NotFound errors with 404 and the rest with 400.
+// Any other error a service returns comes from a store and is a 500.
+type Error struct {
+	Message  string
+	NotFound bool
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrCustomerNotFound     = &Error{Message: "Customer not found", NotFound: true}
+	ErrOrderNotFound        = &Error{Message: "Order not found", NotFound: true}
+	ErrOrderCustomerMissing = &Error{Message: "Customer not found"}
+	ErrInvalidGender        = &Error{Message: "Invalid gender"}
+	ErrInvalidPaymentStatus = &Error{Message: "Invalid payment status"}
+	ErrEmailExists          = &Error{Message: "Email already exists"}
+)
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/store"
+)
+
+type OrderInput struct {
+	CustomerID    uint
+	ProductName   string
+	Quantity      int
+	TotalPrice    float64
+	PaymentStatus string
+}
+
+// OrderService holds the rules for orders: the customer must belong to the
+// same tenant, payment status is paid or unpaid, and paid_at is stamped when
+// an order becomes paid and cleared when it doesn't.
+type OrderService struct {
+	Orders    store.OrderStore
+	Customers store.CustomerStore
+
+	// Now is the clock paid_at is read from.
+	Now func() time.Time
+}
+
+func NewOrderService(orders store.OrderStore, customers store.CustomerStore) *OrderService {
+	return &OrderService{Orders: orders, Customers: customers, Now: time.Now}
+}
+
+// validate checks input and lower cases its payment status.
+func (s *OrderService) validate(ctx context.Context, tenantID uint, input *OrderInput) error {
+	_, err := s.Customers.Get(ctx, tenantID, input.CustomerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrOrderCustomerMissing
+	}
+	if err != nil {
+		return err
+	}
+
+	input.PaymentStatus = strings.ToLower(input.PaymentStatus)
+	if input.PaymentStatus != "paid" && input.PaymentStatus != "unpaid" {
+		return ErrInvalidPaymentStatus
+	}
+	return nil
+}
+
+func (s *OrderService) stampPaidAt(order *models.Order) {
+	if order.PaymentStatus == "paid" {
+		paidAt := s.Now()
+		order.PaidAt = &paidAt
+	} else {
+		order.PaidAt = nil
+	}
+}
+
+func (s *OrderService) List(ctx context.Context, tenantID uint, page store.Page) ([]models.Order, int64, error) {
+	return s.Orders.List(ctx, tenantID, page)
+}
+
+func (s *OrderService) Get(ctx context.Context, tenantID uint, id uint) (*models.Order, error) {
+	order, err := s.Orders.Get(ctx, tenantID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return order, err
+}
+
+func (s *OrderService) Create(ctx context.Context, tenantID uint, input OrderInput) (*models.Order, error) {
+	if err := s.validate(ctx, tenantID, &input); err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		TenantID:      tenantID,
+		CustomerID:    input.CustomerID,
+		ProductName:   input.ProductName,
+		Quantity:      input.Quantity,
+		TotalPrice:    input.TotalPrice,
+		PaymentStatus: input.PaymentStatus,
+	}
+	s.stampPaidAt(order)
+	if err := s.Orders.Create(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Update changes everything but the customer, which an order keeps for life.
+// input.CustomerID must still name an existing customer.
+func (s *OrderService) Update(ctx context.Context, tenantID uint, id uint, input OrderInput) (*models.Order, error) {
+	order, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(ctx, tenantID, &input); err != nil {
+		return nil, err
+	}
+
+	order.ProductName = input.ProductName
+	order.Quantity = input.Quantity
+	order.TotalPrice = input.TotalPrice
+	order.PaymentStatus = input.PaymentStatus
+	s.stampPaidAt(order)
+	if err := s.Orders.Update(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *OrderService) Delete(ctx context.Context, tenantID uint, id uint) error {
+	order, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	return s.Orders.Delete(ctx, order)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/store"
+)
+
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newOrderService returns an OrderService whose clock is stopped at testNow,
+// with a customer in tenant 1 and one in tenant 2.
+func newOrderService(t *testing.T) (*OrderService, *models.Customer, *models.Customer) {
+	t.Helper()
+
+	customers := store.NewMemoryCustomerStore()
+	s := NewOrderService(store.NewMemoryOrderStore(), customers)
+	s.Now = func() time.Time { return testNow }
+
+	var created []*models.Customer
+	for tenantID := uint(1); tenantID <= 2; tenantID++ {
+		customer := &models.Customer{TenantID: tenantID, Name: "Cust", Email: "cust@example.com", Gender: "male"}
+		if err := customers.Create(context.Background(), customer); err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, customer)
+	}
+	return s, created[0], created[1]
+}
+
+func TestCreateOrder(t *testing.T) {
+	s, customer, otherTenantCustomer := newOrderService(t)
+
+	tests := []struct {
+		name       string
+		input      OrderInput
+		wantErr    error
+		wantStatus string
+		wantPaidAt *time.Time
+	}{
+		{
+			name:       "unpaid",
+			input:      OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 1, PaymentStatus: "unpaid"},
+			wantStatus: "unpaid",
+		},
+		{
+			name:       "paid is stamped",
+			input:      OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 1, PaymentStatus: "PAID"},
+			wantStatus: "paid",
+			wantPaidAt: &testNow,
+		},
+		{
+			name:    "unknown payment status",
+			input:   OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 1, PaymentStatus: "refunded"},
+			wantErr: ErrInvalidPaymentStatus,
+		},
+		{
+			name:    "missing customer",
+			input:   OrderInput{CustomerID: 999, ProductName: "Book", Quantity: 1, PaymentStatus: "paid"},
+			wantErr: ErrOrderCustomerMissing,
+		},
+		{
+			name:    "customer of another tenant",
+			input:   OrderInput{CustomerID: otherTenantCustomer.ID, ProductName: "Book", Quantity: 1, PaymentStatus: "paid"},
+			wantErr: ErrOrderCustomerMissing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := s.Create(context.Background(), 1, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if order.TenantID != 1 || order.PaymentStatus != tt.wantStatus {
+				t.Errorf("got tenant %d and status %q, want 1 and %q", order.TenantID, order.PaymentStatus, tt.wantStatus)
+			}
+			if !samePaidAt(order.PaidAt, tt.wantPaidAt) {
+				t.Errorf("got paid_at %v, want %v", order.PaidAt, tt.wantPaidAt)
+			}
+		})
+	}
+}
+
+func TestUpdateOrder(t *testing.T) {
+	ctx := context.Background()
+	s, customer, otherTenantCustomer := newOrderService(t)
+
+	tests := []struct {
+		name       string
+		tenantID   uint
+		input      OrderInput
+		wantErr    error
+		wantPaidAt *time.Time
+	}{
+		{
+			name:       "becomes paid",
+			tenantID:   1,
+			input:      OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 2, PaymentStatus: "paid"},
+			wantPaidAt: &testNow,
+		},
+		{
+			name:     "becomes unpaid",
+			tenantID: 1,
+			input:    OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 2, PaymentStatus: "unpaid"},
+		},
+		{
+			name:     "unknown payment status",
+			tenantID: 1,
+			input:    OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 2, PaymentStatus: "refunded"},
+			wantErr:  ErrInvalidPaymentStatus,
+		},
+		{
+			name:     "customer of another tenant",
+			tenantID: 1,
+			input:    OrderInput{CustomerID: otherTenantCustomer.ID, ProductName: "Book", Quantity: 2, PaymentStatus: "paid"},
+			wantErr:  ErrOrderCustomerMissing,
+		},
+		{
+			name:     "order of another tenant",
+			tenantID: 2,
+			input:    OrderInput{CustomerID: otherTenantCustomer.ID, ProductName: "Book", Quantity: 2, PaymentStatus: "paid"},
+			wantErr:  ErrOrderNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := s.Create(ctx, 1, OrderInput{CustomerID: customer.ID, ProductName: "Pen", Quantity: 1, PaymentStatus: "paid"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			updated, err := s.Update(ctx, tt.tenantID, order.ID, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if updated.ProductName != tt.input.ProductName || updated.Quantity != tt.input.Quantity || updated.CustomerID != customer.ID {
+				t.Errorf("got %+v", updated)
+			}
+			if !samePaidAt(updated.PaidAt, tt.wantPaidAt) {
+				t.Errorf("got paid_at %v, want %v", updated.PaidAt, tt.wantPaidAt)
+			}
+		})
+	}
+}
+
+func TestOrdersStayInTheirTenant(t *testing.T) {
+	ctx := context.Background()
+	s, customer, _ := newOrderService(t)
+	order, err := s.Create(ctx, 1, OrderInput{CustomerID: customer.ID, ProductName: "Book", Quantity: 1, PaymentStatus: "unpaid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(ctx, 2, order.ID); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("get: got error %v, want %v", err, ErrOrderNotFound)
+	}
+	if err := s.Delete(ctx, 2, order.ID); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("delete: got error %v, want %v", err, ErrOrderNotFound)
+	}
+	if _, err := s.Get(ctx, 1, order.ID); err != nil {
+		t.Errorf("got error %v after the other tenant's calls", err)
+	}
+}
+
+func samePaidAt(got *time.Time, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
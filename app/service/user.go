@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+	"github.com/fajaaro/dbo/app/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type RegisterInput struct {
+	Email        string
+	Password     string
+	Organization string
+}
+
+// UserService holds the rules for signing up: the password passes the
+// policy, the email is unused, and the new user admins a new tenant named
+// after their organization, or their email when they gave none.
+type UserService struct {
+	Users store.UserStore
+
+	// Policy returns the password policy in force, read on every call so
+	// configuration changes apply.
+	Policy func() password.Policy
+}
+
+func NewUserService(users store.UserStore, policy func() password.Policy) *UserService {
+	return &UserService{Users: users, Policy: policy}
+}
+
+// Register creates the account. A rejected password comes back as the
+// *password.PolicyError.
+func (s *UserService) Register(ctx context.Context, input RegisterInput) (*models.User, error) {
+	if err := s.Policy().Validate(input.Password, input.Email); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.Users.EmailExists(ctx, input.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrEmailExists
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("Failed to hash password")
+	}
+
+	tenantName := input.Organization
+	if tenantName == "" {
+		tenantName = input.Email
+	}
+
+	user := &models.User{
+		Email:    input.Email,
+		Password: string(hashedPassword),
+	}
+	err = s.Users.CreateWithTenant(ctx, user, &models.Tenant{Name: tenantName}, models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
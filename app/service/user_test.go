@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fajaaro/dbo/app/models"
+	"github.com/fajaaro/dbo/app/password"
+	"github.com/fajaaro/dbo/app/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tenantRecorder keeps the tenants users are created with, which the memory
+// store doesn't.
+type tenantRecorder struct {
+	*store.MemoryUserStore
+	tenants map[string]models.Tenant
+}
+
+func (s *tenantRecorder) CreateWithTenant(ctx context.Context, user *models.User, tenant *models.Tenant, role string) error {
+	if err := s.MemoryUserStore.CreateWithTenant(ctx, user, tenant, role); err != nil {
+		return err
+	}
+	s.tenants[user.Email] = *tenant
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	users := &tenantRecorder{MemoryUserStore: store.NewMemoryUserStore(), tenants: map[string]models.Tenant{}}
+	s := NewUserService(users, func() password.Policy {
+		return password.Policy{MinLength: 12, MaxLength: 72}
+	})
+	if _, err := s.Register(ctx, RegisterInput{Email: "taken@example.com", Password: "horse-battery-42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		input      RegisterInput
+		wantErr    error
+		wantCode   string
+		wantTenant string
+	}{
+		{
+			name:       "named after the organization",
+			input:      RegisterInput{Email: "alice@example.com", Password: "horse-battery-42", Organization: "Acme"},
+			wantTenant: "Acme",
+		},
+		{
+			name:       "named after the email",
+			input:      RegisterInput{Email: "bob@example.com", Password: "horse-battery-42"},
+			wantTenant: "bob@example.com",
+		},
+		{
+			name:    "email taken",
+			input:   RegisterInput{Email: "taken@example.com", Password: "horse-battery-42"},
+			wantErr: ErrEmailExists,
+		},
+		{
+			name:     "password too short",
+			input:    RegisterInput{Email: "carol@example.com", Password: "short"},
+			wantCode: "password_too_short",
+		},
+		{
+			name:     "password contains the email",
+			input:    RegisterInput{Email: "dave@example.com", Password: "dave-battery-42"},
+			wantCode: "password_contains_email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.Register(ctx, tt.input)
+			if tt.wantCode != "" {
+				var policyErr *password.PolicyError
+				if !errors.As(err, &policyErr) || policyErr.Code != tt.wantCode {
+					t.Fatalf("got error %v, want a policy error %s", err, tt.wantCode)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if tenant := users.tenants[user.Email]; tenant.Name != tt.wantTenant || user.TenantID != tenant.ID {
+				t.Errorf("got tenant %+v for user of tenant %d, want one named %q", tenant, user.TenantID, tt.wantTenant)
+			}
+			if len(user.Roles) != 1 || user.Roles[0].Name != models.RoleAdmin {
+				t.Errorf("got roles %+v, want admin", user.Roles)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(tt.input.Password)) != nil {
+				t.Error("password isn't stored as its bcrypt hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fajaaro/dbo/app/dialect"
+	"github.com/fajaaro/dbo/app/models"
+	"gorm.io/gorm"
+)
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func tenantScope(tenantID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_id = ?", tenantID)
+	}
+}
+
+type GormCustomerStore struct {
+	DB *gorm.DB
+}
+
+func NewGormCustomerStore(db *gorm.DB) *GormCustomerStore {
+	return &GormCustomerStore{DB: db}
+}
+
+func (s *GormCustomerStore) List(ctx context.Context, tenantID uint, page Page) ([]models.Customer, int64, error) {
+	query := s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).Model(&models.Customer{})
+	if page.Search != "" {
+		ilike := dialect.Of(s.DB).ILike
+		search := "%" + page.Search + "%"
+		query = query.Where(ilike("name")+" OR "+ilike("email")+" OR "+ilike("phone_number"), search, search, search)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var customers []models.Customer
+	err := query.Offset(page.Offset).Limit(page.Limit).Find(&customers).Error
+	return customers, count, err
+}
+
+func (s *GormCustomerStore) Get(ctx context.Context, tenantID uint, id uint) (*models.Customer, error) {
+	var customer models.Customer
+	err := s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).First(&customer, id).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &customer, nil
+}
+
+func (s *GormCustomerStore) EmailExists(ctx context.Context, tenantID uint, email string) (bool, error) {
+	var count int64
+	err := s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).Model(&models.Customer{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormCustomerStore) Create(ctx context.Context, customer *models.Customer) error {
+	return s.DB.WithContext(ctx).Create(customer).Error
+}
+
+func (s *GormCustomerStore) Update(ctx context.Context, customer *models.Customer) error {
+	return s.DB.WithContext(ctx).Save(customer).Error
+}
+
+func (s *GormCustomerStore) Delete(ctx context.Context, customer *models.Customer) error {
+	return s.DB.WithContext(ctx).Delete(customer).Error
+}
+
+type GormOrderStore struct {
+	DB *gorm.DB
+}
+
+func NewGormOrderStore(db *gorm.DB) *GormOrderStore {
+	return &GormOrderStore{DB: db}
+}
+
+func (s *GormOrderStore) List(ctx context.Context, tenantID uint, page Page) ([]models.Order, int64, error) {
+	query := s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).Model(&models.Order{})
+	if page.Search != "" {
+		query = query.Where(dialect.Of(s.DB).ILike("product_name"), "%"+page.Search+"%")
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []models.Order
+	err := query.Offset(page.Offset).Limit(page.Limit).Find(&orders).Error
+	return orders, count, err
+}
+
+func (s *GormOrderStore) Get(ctx context.Context, tenantID uint, id uint) (*models.Order, error) {
+	var order models.Order
+	err := s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).First(&order, id).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (s *GormOrderStore) Create(ctx context.Context, order *models.Order) error {
+	return s.DB.WithContext(ctx).Create(order).Error
+}
+
+func (s *GormOrderStore) Update(ctx context.Context, order *models.Order) error {
+	return s.DB.WithContext(ctx).Save(order).Error
+}
+
+func (s *GormOrderStore) Delete(ctx context.Context, order *models.Order) error {
+	return s.DB.WithContext(ctx).Delete(order).Error
+}
+
+func (s *GormOrderStore) DeleteByCustomer(ctx context.Context, tenantID uint, customerID uint) error {
+	return s.DB.WithContext(ctx).Scopes(tenantScope(tenantID)).Where("customer_id = ?", customerID).Delete(&models.Order{}).Error
+}
+
+type GormUserStore struct {
+	DB *gorm.DB
+}
+
+func NewGormUserStore(db *gorm.DB) *GormUserStore {
+	return &GormUserStore{DB: db}
+}
+
+func (s *GormUserStore) EmailExists(ctx context.Context, email string) (bool, error) {
+	var count int64
+	err := s.DB.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormUserStore) CreateWithTenant(ctx context.Context, user *models.User, tenant *models.Tenant, role string) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		user.TenantID = tenant.ID
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		var found models.Role
+		err := tx.Where("name = ?", role).First(&found).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("role %q not found", role)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(user).Association("Roles").Append(&found)
+	})
+}
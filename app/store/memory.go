@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fajaaro/dbo/app/models"
+)
+
+// The memory stores keep everything in process. They behave like the GORM
+// stores, so services and handlers can be exercised without a database.
+
+func contains(value string, search string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(search))
+}
+
+// paginate returns the part of n items selected by page as [from, to).
+func paginate(n int, page Page) (int, int) {
+	from := page.Offset
+	if from < 0 {
+		from = 0
+	}
+	if from > n {
+		from = n
+	}
+	to := n
+	if page.Limit >= 0 && from+page.Limit < n {
+		to = from + page.Limit
+	}
+	return from, to
+}
+
+type MemoryCustomerStore struct {
+	mu        sync.RWMutex
+	nextID    uint
+	customers map[uint]models.Customer
+}
+
+func NewMemoryCustomerStore() *MemoryCustomerStore {
+	return &MemoryCustomerStore{customers: map[uint]models.Customer{}}
+}
+
+func (s *MemoryCustomerStore) List(ctx context.Context, tenantID uint, page Page) ([]models.Customer, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	customers := []models.Customer{}
+	for _, customer := range s.customers {
+		if customer.TenantID != tenantID {
+			continue
+		}
+		if page.Search != "" && !contains(customer.Name, page.Search) && !contains(customer.Email, page.Search) && !contains(customer.PhoneNumber, page.Search) {
+			continue
+		}
+		customers = append(customers, customer)
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+
+	from, to := paginate(len(customers), page)
+	return customers[from:to], int64(len(customers)), nil
+}
+
+func (s *MemoryCustomerStore) Get(ctx context.Context, tenantID uint, id uint) (*models.Customer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	customer, ok := s.customers[id]
+	if !ok || customer.TenantID != tenantID {
+		return nil, ErrNotFound
+	}
+	return &customer, nil
+}
+
+func (s *MemoryCustomerStore) EmailExists(ctx context.Context, tenantID uint, email string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, customer := range s.customers {
+		if customer.TenantID == tenantID && customer.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryCustomerStore) Create(ctx context.Context, customer *models.Customer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	customer.ID = s.nextID
+	customer.CreatedAt = time.Now()
+	customer.UpdatedAt = customer.CreatedAt
+	s.customers[customer.ID] = *customer
+	return nil
+}
+
+func (s *MemoryCustomerStore) Update(ctx context.Context, customer *models.Customer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	customer.UpdatedAt = time.Now()
+	s.customers[customer.ID] = *customer
+	return nil
+}
+
+func (s *MemoryCustomerStore) Delete(ctx context.Context, customer *models.Customer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.customers, customer.ID)
+	return nil
+}
+
+type MemoryOrderStore struct {
+	mu     sync.RWMutex
+	nextID uint
+	orders map[uint]models.Order
+}
+
+func NewMemoryOrderStore() *MemoryOrderStore {
+	return &MemoryOrderStore{orders: map[uint]models.Order{}}
+}
+
+func (s *MemoryOrderStore) List(ctx context.Context, tenantID uint, page Page) ([]models.Order, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := []models.Order{}
+	for _, order := range s.orders {
+		if order.TenantID != tenantID {
+			continue
+		}
+		if page.Search != "" && !contains(order.ProductName, page.Search) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+
+	from, to := paginate(len(orders), page)
+	return orders[from:to], int64(len(orders)), nil
+}
+
+func (s *MemoryOrderStore) Get(ctx context.Context, tenantID uint, id uint) (*models.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[id]
+	if !ok || order.TenantID != tenantID {
+		return nil, ErrNotFound
+	}
+	return &order, nil
+}
+
+func (s *MemoryOrderStore) Create(ctx context.Context, order *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	order.ID = s.nextID
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	s.orders[order.ID] = *order
+	return nil
+}
+
+func (s *MemoryOrderStore) Update(ctx context.Context, order *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order.UpdatedAt = time.Now()
+	s.orders[order.ID] = *order
+	return nil
+}
+
+func (s *MemoryOrderStore) Delete(ctx context.Context, order *models.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.orders, order.ID)
+	return nil
+}
+
+func (s *MemoryOrderStore) DeleteByCustomer(ctx context.Context, tenantID uint, customerID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, order := range s.orders {
+		if order.TenantID == tenantID && order.CustomerID == customerID {
+			delete(s.orders, id)
+		}
+	}
+	return nil
+}
+
+type MemoryUserStore struct {
+	mu           sync.RWMutex
+	nextID       uint
+	nextTenantID uint
+	users        map[string]models.User
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[string]models.User{}}
+}
+
+func (s *MemoryUserStore) EmailExists(ctx context.Context, email string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.users[email]
+	return ok, nil
+}
+
+func (s *MemoryUserStore) CreateWithTenant(ctx context.Context, user *models.User, tenant *models.Tenant, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextTenantID++
+	tenant.ID = s.nextTenantID
+	s.nextID++
+	user.ID = s.nextID
+	user.TenantID = tenant.ID
+	user.Roles = []models.Role{{Name: role}}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	s.users[user.Email] = *user
+	return nil
+}
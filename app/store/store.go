@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fajaaro/dbo/app/models"
+)
+
+// ErrNotFound is returned when no row of the caller's tenant matches.
+var ErrNotFound = errors.New("record not found")
+
+// Page selects part of a listing. Search is matched case-insensitively, and
+// a negative Limit means no limit.
+type Page struct {
+	Search string
+	Offset int
+	Limit  int
+}
+
+// CustomerStore keeps customers. Every method is scoped to one tenant.
+type CustomerStore interface {
+	List(ctx context.Context, tenantID uint, page Page) ([]models.Customer, int64, error)
+	Get(ctx context.Context, tenantID uint, id uint) (*models.Customer, error)
+	EmailExists(ctx context.Context, tenantID uint, email string) (bool, error)
+	Create(ctx context.Context, customer *models.Customer) error
+	Update(ctx context.Context, customer *models.Customer) error
+	Delete(ctx context.Context, customer *models.Customer) error
+}
+
+// OrderStore keeps orders. Every method is scoped to one tenant.
+type OrderStore interface {
+	List(ctx context.Context, tenantID uint, page Page) ([]models.Order, int64, error)
+	Get(ctx context.Context, tenantID uint, id uint) (*models.Order, error)
+	Create(ctx context.Context, order *models.Order) error
+	Update(ctx context.Context, order *models.Order) error
+	Delete(ctx context.Context, order *models.Order) error
+	DeleteByCustomer(ctx context.Context, tenantID uint, customerID uint) error
+}
+
+// UserStore keeps user accounts. Emails are unique across tenants.
+type UserStore interface {
+	EmailExists(ctx context.Context, email string) (bool, error)
+	// CreateWithTenant creates tenant, then user in it with the role named
+	// role, all or nothing.
+	CreateWithTenant(ctx context.Context, user *models.User, tenant *models.Tenant, role string) error
+}